@@ -1,22 +1,124 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/control"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/livereload"
 	"hotreloader/pkg/optimizer"
+	"hotreloader/pkg/proxy"
 	"hotreloader/pkg/watcher"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
+// subcommands are the first arguments that select a command rather than
+// name the directory to watch
+var subcommands = map[string]bool{"test": true, "rollback": true, "explain": true}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: hotreloader <directory>")
+	command, args, explain := parseArgs(os.Args[1:])
+
+	switch command {
+	case "rollback", "explain":
+		run := runRollback
+		if command == "explain" {
+			run = runExplain
+		}
+		if err := run(args); err != nil {
+			if errors.Is(err, errUsage) {
+				printUsage()
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) < 1 {
+		printUsage()
 		os.Exit(1)
 	}
 
-	dir := os.Args[1]
+	dir := args[0]
+	mode := ""
+	if command == "test" {
+		mode = config.ModeTest
+	}
+
+	// Load optional project configuration
+	cfg, err := config.Load(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if mode != "" {
+		cfg.Mode = mode
+	}
 
 	// Initialize the optimizer with project directory
-	opt := optimizer.NewOptimizer(dir)
+	opt := optimizer.NewOptimizer(dir, cfg)
+	if explain {
+		opt.Events().Subscribe("explain", events.NewExplainLogger(), events.DefaultQueueSize)
+	}
+
+	// Serve the control API used by CLI commands such as rollback
+	ctl, err := control.NewServer(config.StateDir(dir), opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: control API unavailable: %v\n", err)
+	} else {
+		ctl.Start()
+		defer ctl.Close()
+	}
+
+	// Hold requests in front of the application during rebuilds
+	var p *proxy.Proxy
+	if cfg.Proxy.Listen != "" {
+		p, err = proxy.New(cfg.Proxy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Refresh browsers after rebuilds
+	if cfg.LiveReload.Enabled {
+		listen := cfg.LiveReload.Listen
+		if listen == "" && p == nil {
+			listen = livereload.DefaultListen
+		}
+
+		lr := livereload.NewServer(listen)
+		lr.SetProcess(cfg.Proxy.Process)
+		if p != nil {
+			lr.Attach(p)
+		}
+		if err := lr.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: live reload unavailable: %v\n", err)
+		} else {
+			opt.Events().Subscribe("livereload", lr, events.DefaultQueueSize)
+			defer lr.Close()
+			if listen != "" {
+				fmt.Printf("Live reload script: http://localhost%s%s\n", listen, livereload.ScriptPath)
+			}
+		}
+	}
+
+	if p != nil {
+		if err := p.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: proxy unavailable: %v\n", err)
+		} else {
+			opt.Events().Subscribe("proxy", p, events.DefaultQueueSize)
+			defer p.Close()
+			fmt.Printf("Proxy listening on %s -> %s\n", cfg.Proxy.Listen, cfg.Proxy.Target)
+		}
+	}
 
 	// Perform initial build and start the application
 	if err := opt.InitialBuild(); err != nil {
@@ -41,3 +143,129 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseArgs splits the command line into a subcommand, its arguments and
+// whether --explain was given. --explain prints why each rebuild happened.
+// Arguments after "--" are never options, and a directory named like a
+// subcommand is watched when it follows "--" or is the only argument.
+func parseArgs(argv []string) (string, []string, bool) {
+	args := make([]string, 0, len(argv))
+	explain := false
+	literal := false
+	for i, arg := range argv {
+		if arg == "--" {
+			literal = len(args) == 0
+			args = append(args, argv[i+1:]...)
+			break
+		}
+		if arg == "--explain" {
+			explain = true
+		} else {
+			args = append(args, arg)
+		}
+	}
+
+	if len(args) == 0 || literal || !subcommands[args[0]] {
+		return "", args, explain
+	}
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() && len(args) == 1 {
+		return "", args, explain
+	}
+	return args[0], args[1:], explain
+}
+
+// errUsage is returned by commands called with missing arguments
+var errUsage = errors.New("missing arguments")
+
+// printUsage prints the command line usage
+func printUsage() {
+	fmt.Println("Usage: hotreloader [--explain] [--] <directory>")
+	fmt.Println("       hotreloader [--explain] test <directory>")
+	fmt.Println("       hotreloader rollback <directory> [build-id]")
+	fmt.Println("       hotreloader explain <directory> [file]")
+	fmt.Println("Use -- to watch a directory named like a command, as in hotreloader -- test")
+}
+
+// runExplain prints why recent rebuilds of a running hot reloader
+// happened, optionally only those triggered by a file
+func runExplain(args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+
+	file := ""
+	if len(args) > 1 {
+		file = filepath.ToSlash(args[1])
+	}
+
+	explanations, err := control.NewClient(config.StateDir(args[0])).Explanations(file)
+	if err != nil {
+		return err
+	}
+	if len(explanations) == 0 {
+		return fmt.Errorf("no rebuilds to explain")
+	}
+
+	for _, e := range explanations {
+		fmt.Printf("[%s] ", e.Time.Format("15:04:05"))
+		events.PrintExplanation(e)
+	}
+	return nil
+}
+
+// runRollback asks a running hot reloader to start an earlier build.
+// Without a build ID it lists the retained builds and prompts for one.
+func runRollback(args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+
+	client := control.NewClient(config.StateDir(args[0]))
+
+	var id int
+	if len(args) > 1 {
+		parsed, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid build id %q", args[1])
+		}
+		id = parsed
+	} else {
+		builds, err := client.Builds()
+		if err != nil {
+			return err
+		}
+		if len(builds) == 0 {
+			return fmt.Errorf("no retained builds")
+		}
+
+		fmt.Println("Retained builds:")
+		for _, b := range builds {
+			status := ""
+			if b.Good {
+				status = " (good)"
+			}
+			trigger := b.Trigger
+			if trigger == "" {
+				trigger = "initial build"
+			}
+			fmt.Printf("  #%d  %s  %s%s\n", b.ID, b.CreatedAt.Format("15:04:05"), trigger, status)
+		}
+
+		fmt.Print("Build to roll back to: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("no build selected")
+		}
+		parsed, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if err != nil {
+			return fmt.Errorf("invalid build id %q", strings.TrimSpace(line))
+		}
+		id = parsed
+	}
+
+	if err := client.Rollback(id); err != nil {
+		return err
+	}
+	fmt.Printf("Rolled back to build #%d\n", id)
+	return nil
+}
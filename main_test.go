@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("test", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		argv        []string
+		wantCommand string
+		wantArgs    []string
+		wantExplain bool
+	}{
+		{name: "directory", argv: []string{"app"}, wantArgs: []string{"app"}},
+		{name: "explain flag", argv: []string{"--explain", "app"}, wantArgs: []string{"app"}, wantExplain: true},
+		{name: "test mode", argv: []string{"test", "app"}, wantCommand: "test", wantArgs: []string{"app"}},
+		{name: "flag after command", argv: []string{"test", "--explain", "app"}, wantCommand: "test", wantArgs: []string{"app"}, wantExplain: true},
+		{name: "rollback", argv: []string{"rollback", "app", "3"}, wantCommand: "rollback", wantArgs: []string{"app", "3"}},
+		{name: "explain command", argv: []string{"explain", "app", "main.go"}, wantCommand: "explain", wantArgs: []string{"app", "main.go"}},
+		{name: "command without directory", argv: []string{"rollback"}, wantCommand: "rollback", wantArgs: []string{}},
+		{name: "lone existing directory", argv: []string{"test"}, wantArgs: []string{"test"}},
+		{name: "test mode of directory named test", argv: []string{"test", "test"}, wantCommand: "test", wantArgs: []string{"test"}},
+		{name: "directory after separator", argv: []string{"--", "rollback"}, wantArgs: []string{"rollback"}},
+		{name: "flag after separator", argv: []string{"--explain", "--", "--explain"}, wantArgs: []string{"--explain"}, wantExplain: true},
+		{name: "separator after command", argv: []string{"explain", "--", "app"}, wantCommand: "explain", wantArgs: []string{"app"}},
+		{name: "no arguments", argv: nil, wantArgs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, args, explain := parseArgs(tt.argv)
+			if command != tt.wantCommand || !reflect.DeepEqual(args, tt.wantArgs) || explain != tt.wantExplain {
+				t.Errorf("parseArgs(%q) = %q, %q, %v, want %q, %q, %v", tt.argv, command, args, explain, tt.wantCommand, tt.wantArgs, tt.wantExplain)
+			}
+		})
+	}
+}
+
+func TestCommandsReturnUsageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func([]string) error
+		args    []string
+		wantErr string
+	}{
+		{name: "explain without directory", run: runExplain, wantErr: errUsage.Error()},
+		{name: "rollback without directory", run: runRollback, wantErr: errUsage.Error()},
+		{name: "rollback with invalid build id", run: runRollback, args: []string{t.TempDir(), "x"}, wantErr: `invalid build id "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(tt.args); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
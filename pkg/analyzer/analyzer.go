@@ -1,119 +1,151 @@
 package analyzer
 
 import (
-	"bufio"
-	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
+// ExternalPrefix marks dependencies outside the project, such as installed
+// packages, which no project file matches
+const ExternalPrefix = "external:"
+
+// Edge kinds record how a file references a dependency
+const (
+	// EdgeImport is a static import of bindings
+	EdgeImport = "import"
+	// EdgeSideEffect is an import of a module for its side effects only
+	EdgeSideEffect = "side-effect"
+	// EdgeType is a TypeScript import or export of types only
+	EdgeType = "type"
+	// EdgeReExport is an export of another module's bindings
+	EdgeReExport = "re-export"
+	// EdgeDynamic is a dynamic import()
+	EdgeDynamic = "dynamic"
+	// EdgeRequire is a CommonJS require()
+	EdgeRequire = "require"
+)
+
+// Edge is a dependency of a file and how the file references it
+type Edge struct {
+	Target string
+	Kind   string
+}
+
 // DependencyAnalyzer analyzes file dependencies
 type DependencyAnalyzer struct {
-	importPatterns map[string]*regexp.Regexp
+	goTarget    GoTarget
+	pythonRoots []string
 }
 
 // NewDependencyAnalyzer creates a new dependency analyzer
 func NewDependencyAnalyzer() *DependencyAnalyzer {
-	return &DependencyAnalyzer{
-		importPatterns: map[string]*regexp.Regexp{
-			".go":   regexp.MustCompile(`^\s*import\s+(?:"([^"]+)"|([a-zA-Z_]\w*)\s+"([^"]+)")`),
-			".js":   regexp.MustCompile(`(?:import\s+.*?\s+from\s+['"]([^'"]+)['"]|require\s*\(\s*['"]([^'"]+)['"]\s*\))`),
-			".ts":   regexp.MustCompile(`(?:import\s+.*?\s+from\s+['"]([^'"]+)['"]|require\s*\(\s*['"]([^'"]+)['"]\s*\))`),
-			".jsx":  regexp.MustCompile(`(?:import\s+.*?\s+from\s+['"]([^'"]+)['"]|require\s*\(\s*['"]([^'"]+)['"]\s*\))`),
-			".tsx":  regexp.MustCompile(`(?:import\s+.*?\s+from\s+['"]([^'"]+)['"]|require\s*\(\s*['"]([^'"]+)['"]\s*\))`),
-			".py":   regexp.MustCompile(`^\s*(?:from\s+(\S+)\s+import|import\s+(\S+))`),
-		},
-	}
+	return &DependencyAnalyzer{}
+}
+
+// SetGoTarget sets the target whose files Go imports resolve to
+func (a *DependencyAnalyzer) SetGoTarget(target GoTarget) {
+	a.goTarget = target
 }
 
 // AnalyzeDependencies extracts dependencies from a file
 func (a *DependencyAnalyzer) AnalyzeDependencies(filePath string) ([]string, error) {
-	ext := filepath.Ext(filePath)
-	pattern, ok := a.importPatterns[ext]
-	if !ok {
-		// Unsupported file type, no dependencies
-		return []string{}, nil
-	}
-
-	file, err := os.Open(filePath)
+	edges, err := a.AnalyzeEdges(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	dependencies := make(map[string]bool)
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		matches := pattern.FindAllStringSubmatch(line, -1)
-
-		for _, match := range matches {
-			for i := 1; i < len(match); i++ {
-				if match[i] != "" {
-					dep := a.normalizeDependency(match[i], ext)
-					if dep != "" {
-						dependencies[dep] = true
-					}
-				}
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
 
-	// Convert map to slice
-	deps := make([]string, 0, len(dependencies))
-	for dep := range dependencies {
-		deps = append(deps, dep)
+	deps := make([]string, len(edges))
+	for i, e := range edges {
+		deps[i] = e.Target
 	}
-
 	return deps, nil
 }
 
-// normalizeDependency normalizes a dependency path
-func (a *DependencyAnalyzer) normalizeDependency(dep, ext string) string {
-	dep = strings.TrimSpace(dep)
-
-	// Skip empty dependencies
-	if dep == "" {
-		return ""
+// AnalyzeEdges extracts dependencies from a file along with how they are
+// referenced. Only JS and TS distinguish kinds; other imports are
+// EdgeImport.
+func (a *DependencyAnalyzer) AnalyzeEdges(filePath string) ([]Edge, error) {
+	ext := filepath.Ext(filePath)
+	if isJSExt(ext) {
+		return analyzeJS(filePath)
 	}
 
-	// For relative imports in JS/TS, keep them as-is
-	if ext == ".js" || ext == ".ts" || ext == ".jsx" || ext == ".tsx" {
-		return dep
+	var deps []string
+	var err error
+	switch ext {
+	case ".go":
+		deps, err = a.analyzeGo(filePath)
+	case ".py":
+		deps, err = a.analyzePython(filePath)
+	default:
+		// Unsupported file type, no dependencies
+		return []Edge{}, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return dep
+	edges := make([]Edge, len(deps))
+	for i, dep := range deps {
+		edges[i] = Edge{Target: dep, Kind: EdgeImport}
+	}
+	return edges, nil
 }
 
 // DependencyGraph represents a graph of file dependencies
 type DependencyGraph struct {
 	graph map[string][]string
+	kinds map[string]map[string]string
 }
 
 // NewDependencyGraph creates a new dependency graph
 func NewDependencyGraph() *DependencyGraph {
 	return &DependencyGraph{
 		graph: make(map[string][]string),
+		kinds: make(map[string]map[string]string),
 	}
 }
 
 // AddDependency adds a dependency edge to the graph
 func (g *DependencyGraph) AddDependency(file string, deps []string) {
 	g.graph[file] = deps
+	delete(g.kinds, file)
+}
+
+// AddEdges replaces the dependencies of a file, recording their kinds
+func (g *DependencyGraph) AddEdges(file string, edges []Edge) {
+	deps := make([]string, len(edges))
+	kinds := make(map[string]string, len(edges))
+	for i, e := range edges {
+		deps[i] = e.Target
+		kinds[e.Target] = e.Kind
+	}
+	g.graph[file] = deps
+	g.kinds[file] = kinds
+}
+
+// EdgeKind returns how a file references one of the files it depends on,
+// or an empty string if it does not
+func (g *DependencyGraph) EdgeKind(file, dep string) string {
+	absDep := absPath(dep)
+	for _, target := range g.graph[file] {
+		if dependsOn(target, dep, absDep) {
+			if kind, ok := g.kinds[file][target]; ok {
+				return kind
+			}
+			return EdgeImport
+		}
+	}
+	return ""
 }
 
 // GetDependents returns all files that depend on the given file
 func (g *DependencyGraph) GetDependents(file string) []string {
 	dependents := []string{}
+	absFile := absPath(file)
 	for f, deps := range g.graph {
 		for _, dep := range deps {
-			if strings.Contains(dep, filepath.Base(file)) || dep == file {
+			if dependsOn(dep, file, absFile) {
 				dependents = append(dependents, f)
 				break
 			}
@@ -122,24 +154,78 @@ func (g *DependencyGraph) GetDependents(file string) []string {
 	return dependents
 }
 
+// ResolveDependencies returns the files in the graph that a file depends on,
+// matched the same way as GetDependents
+func (g *DependencyGraph) ResolveDependencies(file string) []string {
+	resolved := []string{}
+	for f := range g.graph {
+		if f == file {
+			continue
+		}
+		absF := absPath(f)
+		for _, dep := range g.graph[file] {
+			if dependsOn(dep, f, absF) {
+				resolved = append(resolved, f)
+				break
+			}
+		}
+	}
+	return resolved
+}
+
+// dependsOn reports whether a dependency refers to a file. Resolved
+// dependencies are absolute paths and must match the file exactly; import
+// specifiers match any file with the same base name.
+func dependsOn(dep, file, absFile string) bool {
+	if filepath.IsAbs(dep) {
+		return dep == absFile
+	}
+	if strings.HasPrefix(dep, ExternalPrefix) {
+		return false
+	}
+	return strings.Contains(dep, filepath.Base(file)) || dep == file
+}
+
+// absPath returns the absolute form of a path, or the path itself if it
+// cannot be made absolute
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
 // GetAllAffectedFiles returns all files affected by a change (including transitive deps)
 func (g *DependencyGraph) GetAllAffectedFiles(file string) []string {
+	chains := g.GetAffectedChains(file)
+	affected := make([]string, len(chains))
+	for i, chain := range chains {
+		affected[i] = chain[len(chain)-1]
+	}
+	return affected
+}
+
+// GetAffectedChains returns, for every file affected by a change, the chain
+// of dependents walked from the changed file to it. The first chain is the
+// changed file itself.
+func (g *DependencyGraph) GetAffectedChains(file string) [][]string {
 	visited := make(map[string]bool)
-	affected := []string{}
+	chains := [][]string{}
 
-	var traverse func(string)
-	traverse = func(f string) {
+	var traverse func([]string)
+	traverse = func(chain []string) {
+		f := chain[len(chain)-1]
 		if visited[f] {
 			return
 		}
 		visited[f] = true
-		affected = append(affected, f)
+		chains = append(chains, chain)
 
 		for _, dependent := range g.GetDependents(f) {
-			traverse(dependent)
+			traverse(append(chain[:len(chain):len(chain)], dependent))
 		}
 	}
 
-	traverse(file)
-	return affected
+	traverse([]string{file})
+	return chains
 }
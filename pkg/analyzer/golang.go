@@ -0,0 +1,132 @@
+package analyzer
+
+import (
+	"bufio"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goModule is the module a Go file belongs to, found by its go.mod
+type goModule struct {
+	dir  string
+	path string
+}
+
+// analyzeGo parses the imports of a Go file and resolves those inside its
+// module to the files the imported packages build for the target.
+// Standard library and third-party imports have no files in the project
+// and are left out.
+func (a *DependencyAnalyzer) analyzeGo(filePath string) ([]string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filePath, nil, parser.ImportsOnly)
+	if f == nil {
+		return nil, err
+	}
+	// A syntax error further down still leaves the parsed imports usable
+
+	mod, ok := findGoModule(filepath.Dir(filePath))
+	if !ok {
+		return []string{}, nil
+	}
+
+	dependencies := make(map[string]bool)
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		dir, ok := mod.packageDir(importPath)
+		if !ok {
+			continue
+		}
+		for _, file := range a.goPackageFiles(dir) {
+			dependencies[file] = true
+		}
+	}
+
+	deps := make([]string, 0, len(dependencies))
+	for dep := range dependencies {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// packageDir returns the directory of a package of the module
+func (m goModule) packageDir(importPath string) (string, bool) {
+	if importPath == m.path {
+		return m.dir, true
+	}
+	rel, ok := strings.CutPrefix(importPath, m.path+"/")
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(m.dir, filepath.FromSlash(rel)), true
+}
+
+// findGoModule walks up from dir to the nearest go.mod
+func findGoModule(dir string) (goModule, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return goModule{}, false
+	}
+
+	for {
+		if path, ok := readModulePath(filepath.Join(dir, "go.mod")); ok {
+			return goModule{dir: dir, path: path}, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return goModule{}, false
+		}
+		dir = parent
+	}
+}
+
+// readModulePath reads the module path from a go.mod file
+func readModulePath(goMod string) (string, bool) {
+	file, err := os.Open(goMod)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		rest, ok := strings.CutPrefix(line, "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		path := strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		return path, path != ""
+	}
+	return "", false
+}
+
+// goPackageFiles lists the non-test Go files of a package directory that
+// build for the target
+func (a *DependencyAnalyzer) goPackageFiles(dir string) []string {
+	ctx := a.goTarget.context()
+	bp, err := ctx.ImportDir(dir, 0)
+	if bp == nil || (err != nil && len(bp.GoFiles) == 0) {
+		return nil
+	}
+
+	var files []string
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
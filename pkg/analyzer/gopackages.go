@@ -0,0 +1,401 @@
+package analyzer
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GoTarget is the platform and build tags Go files are selected for
+type GoTarget struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// NewGoTarget creates a target, defaulting an empty GOOS or GOARCH to the
+// environment and then to the host
+func NewGoTarget(goos, goarch string, tags []string) GoTarget {
+	if goos == "" {
+		goos = build.Default.GOOS
+	}
+	if goarch == "" {
+		goarch = build.Default.GOARCH
+	}
+	return GoTarget{GOOS: goos, GOARCH: goarch, Tags: tags}
+}
+
+// context returns the build context matching files for the target. As with
+// the go command, cgo is off when cross-compiling unless CGO_ENABLED is set.
+func (t GoTarget) context() build.Context {
+	ctx := build.Default
+	if t.GOOS != "" {
+		ctx.GOOS = t.GOOS
+	}
+	if t.GOARCH != "" {
+		ctx.GOARCH = t.GOARCH
+	}
+	ctx.BuildTags = t.Tags
+	if (ctx.GOOS != build.Default.GOOS || ctx.GOARCH != build.Default.GOARCH) && os.Getenv("CGO_ENABLED") == "" {
+		ctx.CgoEnabled = false
+	}
+	return ctx
+}
+
+// GoPackage is a Go package of the project. GoFiles are the files built
+// for the target; test files are kept apart, with XTestGoFiles forming the
+// external _test package. Imports only list packages of the project.
+type GoPackage struct {
+	ImportPath     string
+	Dir            string
+	Name           string
+	GoFiles        []string
+	IgnoredGoFiles []string
+	TestGoFiles    []string
+	XTestGoFiles   []string
+	Imports        []string
+	TestImports    []string
+	XTestImports   []string
+
+	// All imports, kept to resolve again when packages are added
+	allImports      []string
+	allTestImports  []string
+	allXTestImports []string
+}
+
+// IsCommand reports whether the package builds a binary
+func (p *GoPackage) IsCommand() bool {
+	return p.Name == "main"
+}
+
+// HasTests reports whether the package has test files
+func (p *GoPackage) HasTests() bool {
+	return len(p.TestGoFiles) > 0 || len(p.XTestGoFiles) > 0
+}
+
+// GoGraph is the package import graph of a Go module for a target. It is
+// safe for concurrent use and can be updated as packages change.
+type GoGraph struct {
+	mu            sync.RWMutex
+	module        goModule
+	ctx           build.Context
+	packages      map[string]*GoPackage
+	byDir         map[string]*GoPackage
+	importers     map[string][]string
+	testImporters map[string][]string
+}
+
+// GoImpact lists the packages a change affects. Packages must be compiled
+// again, Commands are the main packages among them that need relinking and
+// Tests are the packages whose tests must run again.
+type GoImpact struct {
+	Packages []string
+	Commands []string
+	Tests    []string
+}
+
+// LoadGoGraph parses the packages of the module containing dir for a
+// target. Hidden directories, testdata, vendor and nested modules are
+// skipped.
+func LoadGoGraph(dir string, target GoTarget) (*GoGraph, error) {
+	mod, ok := findGoModule(dir)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	g := &GoGraph{
+		module:   mod,
+		ctx:      target.context(),
+		packages: make(map[string]*GoPackage),
+		byDir:    make(map[string]*GoPackage),
+	}
+
+	err := filepath.WalkDir(mod.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != mod.dir && skipGoDir(path) {
+			return filepath.SkipDir
+		}
+
+		if pkg := g.importDir(path); pkg != nil {
+			g.packages[pkg.ImportPath] = pkg
+			g.byDir[pkg.Dir] = pkg
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	g.index()
+	return g, nil
+}
+
+// skipGoDir reports whether a directory below the module root holds no
+// packages of the module
+func skipGoDir(dir string) bool {
+	name := filepath.Base(dir)
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
+
+// Update reads the package in a directory again after one of its Go files
+// was added, changed or removed. A directory that no longer holds Go files
+// is dropped from the graph. Directories outside the module, or skipped
+// when loading it, are ignored.
+func (g *GoGraph) Update(dir string) {
+	dir = absPath(dir)
+	for d := dir; d != g.module.dir; d = filepath.Dir(d) {
+		if d == filepath.Dir(d) || skipGoDir(d) {
+			return
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if old, ok := g.byDir[dir]; ok {
+		delete(g.packages, old.ImportPath)
+		delete(g.byDir, dir)
+	}
+	if pkg := g.importDir(dir); pkg != nil {
+		g.packages[pkg.ImportPath] = pkg
+		g.byDir[pkg.Dir] = pkg
+	}
+	g.index()
+}
+
+// index resolves the imports of every package against the packages of the
+// module and rebuilds the reverse import graph
+func (g *GoGraph) index() {
+	g.importers = make(map[string][]string)
+	g.testImporters = make(map[string][]string)
+
+	for _, pkg := range g.packages {
+		pkg.Imports = g.internal(pkg.allImports)
+		pkg.TestImports = g.internal(pkg.allTestImports)
+		pkg.XTestImports = g.internal(pkg.allXTestImports)
+
+		for _, imp := range pkg.Imports {
+			g.importers[imp] = append(g.importers[imp], pkg.ImportPath)
+		}
+		for _, imp := range append(pkg.TestImports, pkg.XTestImports...) {
+			g.testImporters[imp] = append(g.testImporters[imp], pkg.ImportPath)
+		}
+	}
+}
+
+// importDir reads the package in a directory, or returns nil if it has no
+// Go files
+func (g *GoGraph) importDir(dir string) *GoPackage {
+	bp, err := g.ctx.ImportDir(dir, 0)
+	if bp == nil || (err != nil && len(bp.GoFiles)+len(bp.TestGoFiles)+len(bp.XTestGoFiles)+len(bp.IgnoredGoFiles) == 0) {
+		return nil
+	}
+
+	importPath := g.module.path
+	if rel, err := filepath.Rel(g.module.dir, dir); err == nil && rel != "." {
+		importPath += "/" + filepath.ToSlash(rel)
+	}
+
+	join := func(names []string) []string {
+		files := make([]string, len(names))
+		for i, name := range names {
+			files[i] = filepath.Join(dir, name)
+		}
+		return files
+	}
+
+	return &GoPackage{
+		ImportPath:      importPath,
+		Dir:             dir,
+		Name:            bp.Name,
+		GoFiles:         join(append(bp.GoFiles, bp.CgoFiles...)),
+		IgnoredGoFiles:  join(bp.IgnoredGoFiles),
+		TestGoFiles:     join(bp.TestGoFiles),
+		XTestGoFiles:    join(bp.XTestGoFiles),
+		allImports:      bp.Imports,
+		allTestImports:  bp.TestImports,
+		allXTestImports: bp.XTestImports,
+	}
+}
+
+// internal keeps the import paths of packages in the module
+func (g *GoGraph) internal(imports []string) []string {
+	var result []string
+	for _, imp := range imports {
+		if _, ok := g.packages[imp]; ok {
+			result = append(result, imp)
+		}
+	}
+	return result
+}
+
+// Len returns the number of packages
+func (g *GoGraph) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.packages)
+}
+
+// Package returns a package by import path
+func (g *GoGraph) Package(importPath string) (*GoPackage, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pkg, ok := g.packages[importPath]
+	return pkg, ok
+}
+
+// Tested returns the import paths of the packages that have tests
+func (g *GoGraph) Tested() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var tested []string
+	for importPath, pkg := range g.packages {
+		if pkg.HasTests() {
+			tested = append(tested, importPath)
+		}
+	}
+	sort.Strings(tested)
+	return tested
+}
+
+// PackageOf returns the package in the directory of a file
+func (g *GoGraph) PackageOf(file string) (*GoPackage, bool) {
+	return g.PackageInDir(filepath.Dir(file))
+}
+
+// PackageInDir returns the package in a directory
+func (g *GoGraph) PackageInDir(dir string) (*GoPackage, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pkg, ok := g.byDir[absPath(dir)]
+	return pkg, ok
+}
+
+// PackageContaining returns the package whose directory contains a file,
+// looking in parent directories for files such as testdata that are not
+// Go sources
+func (g *GoGraph) PackageContaining(file string) (*GoPackage, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for dir := filepath.Dir(absPath(file)); ; dir = filepath.Dir(dir) {
+		if pkg, ok := g.byDir[dir]; ok {
+			return pkg, true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return nil, false
+		}
+	}
+}
+
+// Importers returns the packages importing a package, directly or
+// transitively, not counting test imports
+func (g *GoGraph) Importers(importPath string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.importersOf(importPath)
+}
+
+// importersOf is Importers with the lock held
+func (g *GoGraph) importersOf(importPath string) []string {
+	visited := map[string]bool{importPath: true}
+	queue := []string{importPath}
+	var result []string
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, importer := range g.importers[pkg] {
+			if !visited[importer] {
+				visited[importer] = true
+				result = append(result, importer)
+				queue = append(queue, importer)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Impact returns what a change to a file affects. A file excluded by build
+// constraints affects nothing; a test file only affects its package's
+// tests; go.mod and go.sum affect every package. Any other file, including
+// one that does not parse, affects its package and the packages importing
+// it.
+func (g *GoGraph) Impact(file string) GoImpact {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	switch filepath.Base(file) {
+	case "go.mod", "go.sum":
+		all := make([]string, 0, len(g.packages))
+		for importPath := range g.packages {
+			all = append(all, importPath)
+		}
+		return g.impactOf(all)
+	}
+
+	abs := absPath(file)
+	pkg, ok := g.byDir[filepath.Dir(abs)]
+	if !ok {
+		return GoImpact{}
+	}
+
+	switch {
+	case slices.Contains(pkg.IgnoredGoFiles, abs):
+		return GoImpact{}
+	case slices.Contains(pkg.TestGoFiles, abs), slices.Contains(pkg.XTestGoFiles, abs):
+		return GoImpact{Tests: []string{pkg.ImportPath}}
+	default:
+		return g.impactOf(append([]string{pkg.ImportPath}, g.importersOf(pkg.ImportPath)...))
+	}
+}
+
+// Affected returns what a change to a package affects: the package and
+// the packages importing it, and the tests of those and of packages whose
+// tests import them
+func (g *GoGraph) Affected(importPath string) GoImpact {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if _, ok := g.packages[importPath]; !ok {
+		return GoImpact{}
+	}
+	return g.impactOf(append([]string{importPath}, g.importersOf(importPath)...))
+}
+
+// impactOf completes the impact of compiling a set of packages again
+func (g *GoGraph) impactOf(packages []string) GoImpact {
+	impact := GoImpact{Packages: packages}
+	tests := map[string]bool{}
+	for _, importPath := range packages {
+		pkg := g.packages[importPath]
+		if pkg.IsCommand() {
+			impact.Commands = append(impact.Commands, importPath)
+		}
+		if pkg.HasTests() {
+			tests[importPath] = true
+		}
+		for _, importer := range g.testImporters[importPath] {
+			tests[importer] = true
+		}
+	}
+	for importPath := range tests {
+		impact.Tests = append(impact.Tests, importPath)
+	}
+
+	sort.Strings(impact.Packages)
+	sort.Strings(impact.Commands)
+	sort.Strings(impact.Tests)
+	return impact
+}
@@ -0,0 +1,244 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles creates files below dir from a map of relative paths to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// goModuleFiles is a module whose command imports lib, which imports util.
+// helper is only imported by the tests of lib.
+var goModuleFiles = map[string]string{
+	"go.mod":                       "module example.com/m\n\ngo 1.22\n",
+	"main.go":                      "package main\n\nimport _ \"example.com/m/lib\"\n\nfunc main() {}\n",
+	"lib/lib.go":                   "package lib\n\nimport (\n\t\"fmt\"\n\t_ \"example.com/m/util\"\n)\n\nvar _ = fmt.Sprint\n",
+	"lib/lib_windows.go":           "package lib\n",
+	"lib/lib_test.go":              "package lib\n\nimport _ \"example.com/m/helper\"\n",
+	"lib/testdata/input.txt":       "data\n",
+	"util/util.go":                 "package util\n",
+	"util/util_test.go":            "package util_test\n\nimport _ \"example.com/m/util\"\n",
+	"helper/helper.go":             "package helper\n",
+	"tools/tool.go":                "package main\n\nfunc main() {}\n",
+	"vendor/example.com/x/x.go":    "package x\n",
+	"_scratch/s.go":                "package s\n",
+	"nested/go.mod":                "module example.com/nested\n",
+	"nested/n.go":                  "package nested\n",
+	"lib/testdata/fixture/main.go": "package main\n",
+}
+
+// loadTestGoGraph writes the test module and loads its graph for linux
+func loadTestGoGraph(t *testing.T) (*GoGraph, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, goModuleFiles)
+
+	g, err := LoadGoGraph(dir, NewGoTarget("linux", "amd64", nil))
+	if err != nil {
+		t.Fatalf("LoadGoGraph: %v", err)
+	}
+	return g, dir
+}
+
+func TestLoadGoGraph(t *testing.T) {
+	g, _ := loadTestGoGraph(t)
+
+	want := []string{"example.com/m", "example.com/m/helper", "example.com/m/lib", "example.com/m/tools", "example.com/m/util"}
+	var got []string
+	for _, importPath := range want {
+		if _, ok := g.Package(importPath); ok {
+			got = append(got, importPath)
+		}
+	}
+	if g.Len() != len(want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("packages = %d %v, want %v", g.Len(), got, want)
+	}
+
+	lib, _ := g.Package("example.com/m/lib")
+	if !reflect.DeepEqual(lib.Imports, []string{"example.com/m/util"}) {
+		t.Errorf("lib imports = %v, want only the module's util", lib.Imports)
+	}
+	if len(lib.GoFiles) != 1 || len(lib.IgnoredGoFiles) != 1 {
+		t.Errorf("lib files = %v, ignored %v", lib.GoFiles, lib.IgnoredGoFiles)
+	}
+
+	if tested := g.Tested(); !reflect.DeepEqual(tested, []string{"example.com/m/lib", "example.com/m/util"}) {
+		t.Errorf("Tested = %v", tested)
+	}
+}
+
+func TestGoGraphImpact(t *testing.T) {
+	g, dir := loadTestGoGraph(t)
+
+	tests := []struct {
+		file string
+		want GoImpact
+	}{
+		{
+			file: "util/util.go",
+			want: GoImpact{
+				Packages: []string{"example.com/m", "example.com/m/lib", "example.com/m/util"},
+				Commands: []string{"example.com/m"},
+				Tests:    []string{"example.com/m/lib", "example.com/m/util"},
+			},
+		},
+		{
+			file: "helper/helper.go",
+			want: GoImpact{
+				Packages: []string{"example.com/m/helper"},
+				Tests:    []string{"example.com/m/lib"},
+			},
+		},
+		{
+			file: "tools/tool.go",
+			want: GoImpact{
+				Packages: []string{"example.com/m/tools"},
+				Commands: []string{"example.com/m/tools"},
+			},
+		},
+		{file: "lib/lib_test.go", want: GoImpact{Tests: []string{"example.com/m/lib"}}},
+		{file: "lib/lib_windows.go", want: GoImpact{}},
+		{file: "nested/n.go", want: GoImpact{}},
+		{file: "vendor/example.com/x/x.go", want: GoImpact{}},
+		{
+			file: "go.mod",
+			want: GoImpact{
+				Packages: []string{"example.com/m", "example.com/m/helper", "example.com/m/lib", "example.com/m/tools", "example.com/m/util"},
+				Commands: []string{"example.com/m", "example.com/m/tools"},
+				Tests:    []string{"example.com/m/lib", "example.com/m/util"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := g.Impact(filepath.Join(dir, tt.file))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Impact(%s) = %+v, want %+v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoGraphTargetSelectsFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, goModuleFiles)
+	writeFiles(t, dir, map[string]string{"lib/lib_tag.go": "//go:build special\n\npackage lib\n"})
+
+	tests := []struct {
+		name    string
+		target  GoTarget
+		file    string
+		ignored bool
+	}{
+		{name: "other GOOS", target: NewGoTarget("linux", "amd64", nil), file: "lib/lib_windows.go", ignored: true},
+		{name: "matching GOOS", target: NewGoTarget("windows", "amd64", nil), file: "lib/lib_windows.go"},
+		{name: "tag not set", target: NewGoTarget("linux", "amd64", nil), file: "lib/lib_tag.go", ignored: true},
+		{name: "tag set", target: NewGoTarget("linux", "amd64", []string{"special"}), file: "lib/lib_tag.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := LoadGoGraph(dir, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			impact := g.Impact(filepath.Join(dir, tt.file))
+			if ignored := len(impact.Packages) == 0; ignored != tt.ignored {
+				t.Errorf("%s ignored = %v, want %v", tt.file, ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestGoGraphPackageContaining(t *testing.T) {
+	g, dir := loadTestGoGraph(t)
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "lib/lib.go", want: "example.com/m/lib"},
+		{file: "lib/testdata/input.txt", want: "example.com/m/lib"},
+		{file: "nested/n.go", want: "example.com/m"},
+		{file: "README.md", want: "example.com/m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			pkg, ok := g.PackageContaining(filepath.Join(dir, tt.file))
+			if !ok || pkg.ImportPath != tt.want {
+				t.Errorf("PackageContaining(%s) = %v, %v, want %s", tt.file, pkg, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoGraphUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		write  map[string]string
+		remove string
+		dir    string
+		file   string
+		want   []string
+	}{
+		{
+			name:  "new import",
+			write: map[string]string{"helper/helper.go": "package helper\n\nimport _ \"example.com/m/util\"\n"},
+			dir:   "helper",
+			file:  "util/util.go",
+			want:  []string{"example.com/m", "example.com/m/helper", "example.com/m/lib", "example.com/m/util"},
+		},
+		{
+			name:  "new package",
+			write: map[string]string{"extra/extra.go": "package extra\n\nimport _ \"example.com/m/helper\"\n"},
+			dir:   "extra",
+			file:  "helper/helper.go",
+			want:  []string{"example.com/m/extra", "example.com/m/helper"},
+		},
+		{
+			name:   "removed package",
+			remove: "util",
+			dir:    "util",
+			file:   "lib/lib.go",
+			want:   []string{"example.com/m", "example.com/m/lib"},
+		},
+		{
+			name:  "skipped directory",
+			write: map[string]string{"testdata/t.go": "package t\n\nimport _ \"example.com/m/helper\"\n"},
+			dir:   "testdata",
+			file:  "helper/helper.go",
+			want:  []string{"example.com/m/helper"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, dir := loadTestGoGraph(t)
+			writeFiles(t, dir, tt.write)
+			if tt.remove != "" {
+				os.RemoveAll(filepath.Join(dir, tt.remove))
+			}
+
+			g.Update(filepath.Join(dir, tt.dir))
+			if got := g.Impact(filepath.Join(dir, tt.file)).Packages; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packages affected by %s = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jsExtensions are probed, in order, for specifiers without an extension
+var jsExtensions = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// isJSExt reports whether an extension is one of a JS or TS module
+func isJSExt(ext string) bool {
+	for _, e := range jsExtensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// jsSourceExtensions map the extension of an emitted file to the
+// TypeScript sources it is compiled from, as TypeScript imports name the
+// output file
+var jsSourceExtensions = map[string][]string{
+	".js":  {".ts", ".tsx"},
+	".jsx": {".tsx"},
+	".mjs": {".mts"},
+	".cjs": {".cts"},
+}
+
+// jsConditions are the export conditions tried, in order. Conditions
+// pointing at sources come first, type declarations last.
+var jsConditions = []string{"source", "import", "module", "require", "node", "browser", "default", "types"}
+
+// packageJSON is the subset of package.json used for resolution
+type packageJSON struct {
+	Name    string          `json:"name"`
+	Main    string          `json:"main"`
+	Module  string          `json:"module"`
+	Exports json.RawMessage `json:"exports"`
+	Imports json.RawMessage `json:"imports"`
+}
+
+// tsConfig is the subset of tsconfig.json used for resolution
+type tsConfig struct {
+	Extends         string `json:"extends"`
+	CompilerOptions struct {
+		BaseURL *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// tsPaths are the resolved path settings of a tsconfig.json. Paths are
+// relative to dir, which is baseUrl when it is set.
+type tsPaths struct {
+	baseURL string
+	dir     string
+	paths   map[string][]string
+}
+
+// resolveJS resolves a module specifier imported by a JS or TS file to a
+// project file, or marks it external. Relative specifiers resolve against
+// the importing file; "#" specifiers through the package.json imports map;
+// other specifiers through tsconfig paths and baseUrl, the package's own
+// exports, and finally node_modules, where only workspace packages linked
+// into the project count as project files.
+func resolveJS(spec, importer string) string {
+	dir := filepath.Dir(absPath(importer))
+
+	if isRelativeSpecifier(spec) {
+		base := spec
+		if !filepath.IsAbs(spec) {
+			base = filepath.Join(dir, spec)
+		}
+		if file, ok := probeJS(base); ok {
+			return file
+		}
+		// A missing file keeps its path so it is linked once created
+		return base
+	}
+
+	if strings.HasPrefix(spec, "#") {
+		if pkgDir, pkg, ok := findPackageJSON(dir); ok {
+			if file, ok := resolveExportsMap(pkgDir, pkg.Imports, spec); ok {
+				return file
+			}
+		}
+		return ExternalPrefix + spec
+	}
+
+	if ts, ok := findTSPaths(dir); ok {
+		if file, ok := ts.resolve(spec); ok {
+			return file
+		}
+	}
+
+	name, subpath := splitPackageSpecifier(spec)
+	if pkgDir, pkg, ok := findPackageJSON(dir); ok && pkg.Name == name {
+		if file, ok := resolvePackage(pkgDir, pkg, subpath); ok {
+			return file
+		}
+	}
+	if file, ok := resolveNodeModule(dir, name, subpath); ok {
+		return file
+	}
+	return ExternalPrefix + spec
+}
+
+// analyzeJS lexes a JS or TS file and resolves its module references. A
+// file referenced in several ways keeps one edge, where a type-only
+// reference gives way to any other kind.
+func analyzeJS(filePath string) ([]Edge, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := []Edge{}
+	index := make(map[string]int)
+	for _, ref := range findJSReferences(lexJS(string(src))) {
+		spec := strings.TrimSpace(ref.spec)
+		if spec == "" {
+			continue
+		}
+
+		target := resolveJS(spec, filePath)
+		if i, ok := index[target]; ok {
+			if edges[i].Kind == EdgeType {
+				edges[i].Kind = ref.kind
+			}
+			continue
+		}
+		index[target] = len(edges)
+		edges = append(edges, Edge{Target: target, Kind: ref.kind})
+	}
+	return edges, nil
+}
+
+// isRelativeSpecifier reports whether a specifier names a path rather than
+// a package
+func isRelativeSpecifier(spec string) bool {
+	return spec == "." || spec == ".." || strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || filepath.IsAbs(spec)
+}
+
+// probeJS finds the file a path refers to: the file itself, its TypeScript
+// source, the path with an extension added, or an index file or package
+// entry point when it is a directory
+func probeJS(base string) (string, bool) {
+	if isFile(base) {
+		return base, true
+	}
+
+	ext := filepath.Ext(base)
+	for _, src := range jsSourceExtensions[ext] {
+		if file := strings.TrimSuffix(base, ext) + src; isFile(file) {
+			return file, true
+		}
+	}
+
+	for _, ext := range jsExtensions {
+		if isFile(base + ext) {
+			return base + ext, true
+		}
+	}
+
+	if info, err := os.Stat(base); err == nil && info.IsDir() {
+		if pkg, ok := readPackageJSON(base); ok {
+			for _, entry := range []string{pkg.Module, pkg.Main} {
+				if entry == "" {
+					continue
+				}
+				if file, ok := probeJS(filepath.Join(base, entry)); ok {
+					return file, true
+				}
+			}
+		}
+		for _, ext := range jsExtensions {
+			if file := filepath.Join(base, "index"+ext); isFile(file) {
+				return file, true
+			}
+		}
+	}
+	return "", false
+}
+
+// isFile reports whether a path is an existing regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// splitPackageSpecifier splits a bare specifier into its package name,
+// which may be scoped, and the subpath within the package
+func splitPackageSpecifier(spec string) (string, string) {
+	parts := strings.SplitN(spec, "/", 3)
+	n := 1
+	if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+		n = 2
+	}
+	if len(parts) <= n {
+		return spec, "."
+	}
+	name := strings.Join(parts[:n], "/")
+	return name, "." + strings.TrimPrefix(spec, name)
+}
+
+// resolvePackage resolves a subpath of a package through its exports map,
+// or, without one, against its directory
+func resolvePackage(pkgDir string, pkg packageJSON, subpath string) (string, bool) {
+	if len(pkg.Exports) > 0 {
+		return resolveExportsMap(pkgDir, pkg.Exports, subpath)
+	}
+	return probeJS(filepath.Join(pkgDir, subpath))
+}
+
+// resolveNodeModule resolves a package installed in a node_modules
+// directory above dir. Only packages linked in from the project, such as
+// workspace packages, resolve to files.
+func resolveNodeModule(dir, name, subpath string) (string, bool) {
+	for {
+		pkgDir := filepath.Join(dir, "node_modules", filepath.FromSlash(name))
+		if pkg, ok := readPackageJSON(pkgDir); ok {
+			real, err := filepath.EvalSymlinks(pkgDir)
+			if err != nil || strings.Contains(real+string(filepath.Separator), string(filepath.Separator)+"node_modules"+string(filepath.Separator)) {
+				return "", false
+			}
+			return resolvePackage(real, pkg, subpath)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// resolveExportsMap resolves a subpath, or a "#" specifier, through an
+// exports or imports map
+func resolveExportsMap(pkgDir string, raw json.RawMessage, subpath string) (string, bool) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil || !hasSubpathKeys(m) {
+		// A string, array or conditions object exports only the main entry
+		if subpath != "." {
+			return "", false
+		}
+		return resolveExportTarget(pkgDir, raw, "")
+	}
+
+	if target, ok := m[subpath]; ok {
+		return resolveExportTarget(pkgDir, target, "")
+	}
+
+	// The longest matching pattern wins
+	best, bestMatch := "", ""
+	for key := range m {
+		prefix, suffix, ok := strings.Cut(key, "*")
+		if !ok || !strings.HasPrefix(subpath, prefix) || !strings.HasSuffix(subpath, suffix) || len(subpath) < len(prefix)+len(suffix) {
+			continue
+		}
+		if len(key) > len(best) {
+			best, bestMatch = key, subpath[len(prefix):len(subpath)-len(suffix)]
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return resolveExportTarget(pkgDir, m[best], bestMatch)
+}
+
+// hasSubpathKeys reports whether a map is keyed by subpaths or "#"
+// specifiers rather than by conditions
+func hasSubpathKeys(m map[string]json.RawMessage) bool {
+	for key := range m {
+		if strings.HasPrefix(key, ".") || strings.HasPrefix(key, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveExportTarget resolves the target of an exports or imports entry:
+// a path, with "*" replaced by match, an array of fallbacks or an object
+// of conditions
+func resolveExportTarget(pkgDir string, raw json.RawMessage, match string) (string, bool) {
+	var target string
+	if err := json.Unmarshal(raw, &target); err == nil {
+		if !strings.HasPrefix(target, "./") {
+			// Imports may map to another package, which is not followed
+			return "", false
+		}
+		return probeJS(filepath.Join(pkgDir, strings.ReplaceAll(target, "*", match)))
+	}
+
+	var fallbacks []json.RawMessage
+	if err := json.Unmarshal(raw, &fallbacks); err == nil {
+		for _, fallback := range fallbacks {
+			if file, ok := resolveExportTarget(pkgDir, fallback, match); ok {
+				return file, true
+			}
+		}
+		return "", false
+	}
+
+	var conditions map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &conditions); err == nil {
+		for _, condition := range jsConditions {
+			if nested, ok := conditions[condition]; ok {
+				if file, ok := resolveExportTarget(pkgDir, nested, match); ok {
+					return file, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// findPackageJSON returns the nearest package.json at or above dir
+func findPackageJSON(dir string) (string, packageJSON, bool) {
+	for {
+		if pkg, ok := readPackageJSON(dir); ok {
+			return dir, pkg, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", packageJSON{}, false
+		}
+		dir = parent
+	}
+}
+
+// readPackageJSON reads the package.json in a directory
+func readPackageJSON(dir string) (packageJSON, bool) {
+	var pkg packageJSON
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil || json.Unmarshal(data, &pkg) != nil {
+		return packageJSON{}, false
+	}
+	return pkg, true
+}
+
+// findTSPaths returns the path settings of the nearest tsconfig.json or
+// jsconfig.json at or above dir
+func findTSPaths(dir string) (tsPaths, bool) {
+	for {
+		for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+			if ts, ok := readTSPaths(filepath.Join(dir, name), 0); ok {
+				return ts, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return tsPaths{}, false
+		}
+		dir = parent
+	}
+}
+
+// readTSPaths reads the path settings of a tsconfig file, following
+// relative extends. Settings of the extending file take precedence.
+func readTSPaths(path string, depth int) (tsPaths, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || depth > 8 {
+		return tsPaths{}, false
+	}
+	var cfg tsConfig
+	if err := json.Unmarshal(stripJSONC(data), &cfg); err != nil {
+		return tsPaths{}, false
+	}
+
+	dir := filepath.Dir(path)
+	var ts tsPaths
+	if isRelativeSpecifier(cfg.Extends) {
+		base := filepath.Join(dir, cfg.Extends)
+		if filepath.Ext(base) != ".json" {
+			base += ".json"
+		}
+		ts, _ = readTSPaths(base, depth+1)
+	}
+
+	if cfg.CompilerOptions.BaseURL != nil {
+		ts.baseURL = filepath.Join(dir, *cfg.CompilerOptions.BaseURL)
+	}
+	if cfg.CompilerOptions.Paths != nil {
+		ts.paths = cfg.CompilerOptions.Paths
+		// Without baseUrl, paths are relative to the file declaring them
+		ts.dir = dir
+	}
+	if ts.baseURL != "" {
+		ts.dir = ts.baseURL
+	}
+	return ts, true
+}
+
+// resolve resolves a specifier through the paths aliases, then against
+// baseUrl
+func (ts tsPaths) resolve(spec string) (string, bool) {
+	if targets, ok := ts.paths[spec]; ok {
+		for _, target := range targets {
+			if file, ok := probeJS(filepath.Join(ts.dir, target)); ok {
+				return file, true
+			}
+		}
+	}
+
+	// The pattern with the longest prefix wins
+	best, bestMatch := "", ""
+	for pattern := range ts.paths {
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if !ok || !strings.HasPrefix(spec, prefix) || !strings.HasSuffix(spec, suffix) || len(spec) < len(prefix)+len(suffix) {
+			continue
+		}
+		if best == "" || len(prefix) > strings.Index(best, "*") {
+			best, bestMatch = pattern, spec[len(prefix):len(spec)-len(suffix)]
+		}
+	}
+	if best != "" {
+		for _, target := range ts.paths[best] {
+			if file, ok := probeJS(filepath.Join(ts.dir, strings.ReplaceAll(target, "*", bestMatch))); ok {
+				return file, true
+			}
+		}
+	}
+
+	if ts.baseURL != "" {
+		return probeJS(filepath.Join(ts.baseURL, spec))
+	}
+	return "", false
+}
+
+// stripJSONC removes the comments and trailing commas tsconfig files allow
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			end := min(i+1, len(data))
+			out = append(out, data[start:end]...)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a trailing comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jsProjectFiles is a package with an exports and imports map, tsconfig
+// aliases and a workspace package linked into node_modules
+var jsProjectFiles = map[string]string{
+	"package.json": `{
+		"name": "app",
+		"exports": {".": "./src/index.ts", "./feature/*": "./src/features/*.ts"},
+		"imports": {"#internal/*": "./src/internal/*.ts"}
+	}`,
+	"tsconfig.json": `{
+		// Comments and trailing commas are allowed
+		"compilerOptions": {"baseUrl": ".", "paths": {"@lib/*": ["lib/*"],},},
+	}`,
+	"src/index.ts":                     "export {}\n",
+	"src/util.ts":                      "export {}\n",
+	"src/emitted.ts":                   "export {}\n",
+	"src/dir/index.tsx":                "export {}\n",
+	"src/pkg/package.json":             `{"main": "main.js"}`,
+	"src/pkg/main.js":                  "module.exports = {}\n",
+	"src/features/a.ts":                "export {}\n",
+	"src/internal/b.ts":                "export {}\n",
+	"lib/c.ts":                         "export {}\n",
+	"lib/d/index.js":                   "module.exports = {}\n",
+	"packages/shared/package.json":     `{"name": "shared", "main": "index.js"}`,
+	"packages/shared/index.js":         "module.exports = {}\n",
+	"node_modules/react/package.json":  `{"name": "react", "main": "index.js"}`,
+	"node_modules/react/index.js":      "module.exports = {}\n",
+	"node_modules/@scope/ui/index.js":  "module.exports = {}\n",
+	"node_modules/@scope/ui/README.md": "ui\n",
+}
+
+func TestResolveJS(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, jsProjectFiles)
+	if err := os.Symlink(filepath.Join("..", "packages", "shared"), filepath.Join(dir, "node_modules", "shared")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	importer := filepath.Join(dir, "src", "index.ts")
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "./util", want: "src/util.ts"},
+		{spec: "./emitted.js", want: "src/emitted.ts"},
+		{spec: "./dir", want: "src/dir/index.tsx"},
+		{spec: "./pkg", want: "src/pkg/main.js"},
+		{spec: "../lib/c", want: "lib/c.ts"},
+		{spec: "./missing", want: "src/missing"},
+		{spec: "@lib/c", want: "lib/c.ts"},
+		{spec: "lib/d", want: "lib/d/index.js"},
+		{spec: "#internal/b", want: "src/internal/b.ts"},
+		{spec: "#unknown", want: ExternalPrefix + "#unknown"},
+		{spec: "app", want: "src/index.ts"},
+		{spec: "app/feature/a", want: "src/features/a.ts"},
+		{spec: "shared", want: "packages/shared/index.js"},
+		{spec: "react", want: ExternalPrefix + "react"},
+		{spec: "@scope/ui/button", want: ExternalPrefix + "@scope/ui/button"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			want := tt.want
+			if !strings.HasPrefix(want, ExternalPrefix) {
+				want = filepath.Join(dir, filepath.FromSlash(want))
+			}
+			if got := resolveJS(tt.spec, importer); got != want {
+				t.Errorf("resolveJS(%q) = %q, want %q", tt.spec, got, want)
+			}
+		})
+	}
+}
+
+func TestSplitPackageSpecifier(t *testing.T) {
+	tests := []struct {
+		spec, name, subpath string
+	}{
+		{spec: "react", name: "react", subpath: "."},
+		{spec: "react/jsx-runtime", name: "react", subpath: "./jsx-runtime"},
+		{spec: "@scope/ui", name: "@scope/ui", subpath: "."},
+		{spec: "@scope/ui/button/icon", name: "@scope/ui", subpath: "./button/icon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			name, subpath := splitPackageSpecifier(tt.spec)
+			if name != tt.name || subpath != tt.subpath {
+				t.Errorf("splitPackageSpecifier(%q) = %q, %q, want %q, %q", tt.spec, name, subpath, tt.name, tt.subpath)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// jsTokenKind classifies the tokens of the JS/TS lexer
+type jsTokenKind int
+
+const (
+	jsWord jsTokenKind = iota
+	jsPunct
+	jsString
+	jsTemplate
+	jsRegex
+)
+
+// jsToken is a token of JS or TS source. Text is the value of strings and
+// of templates without substitutions, and the source text otherwise.
+type jsToken struct {
+	kind jsTokenKind
+	text string
+}
+
+// regexKeywords are the keywords after which a slash starts a regex literal
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+// conditionKeywords are the keywords whose parenthesized head is followed
+// by a statement, so a slash after the closing parenthesis starts a regex
+var conditionKeywords = map[string]bool{
+	"if": true, "while": true, "for": true, "with": true,
+}
+
+// lexJS splits JS or TS source into tokens, dropping comments. Strings,
+// template literals, including nested substitutions, and regex literals
+// are read whole, so their content never looks like code.
+func lexJS(src string) []jsToken {
+	var tokens []jsToken
+	// Brace depth of each template substitution being lexed
+	var templates []int
+	depth := 0
+	// Whether each open parenthesis holds the head of a condition keyword,
+	// and whether the last one closed did
+	var parens []bool
+	afterCondition := false
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+
+		case c == '"' || c == '\'':
+			text, n := lexJSString(src[i:])
+			tokens = append(tokens, jsToken{kind: jsString, text: text})
+			i += n
+
+		case c == '`':
+			tok, n, open := lexJSTemplate(src[i+1:])
+			tokens = append(tokens, tok)
+			i += n + 1
+			if open {
+				// A substitution starts an expression, where a slash
+				// starts a regex
+				tokens = append(tokens, jsToken{kind: jsPunct, text: "${"})
+				templates = append(templates, depth)
+			}
+
+		case c == '}' && len(templates) > 0 && templates[len(templates)-1] == depth:
+			// The substitution ends and the template continues
+			templates = templates[:len(templates)-1]
+			_, n, open := lexJSTemplate(src[i+1:])
+			tokens = append(tokens, jsToken{kind: jsTemplate})
+			i += n + 1
+			if open {
+				tokens = append(tokens, jsToken{kind: jsPunct, text: "${"})
+				templates = append(templates, depth)
+			}
+
+		case c == '/' && regexAllowed(tokens, afterCondition):
+			n := lexJSRegex(src[i:])
+			tokens = append(tokens, jsToken{kind: jsRegex, text: src[i : i+n]})
+			i += n
+
+		case isJSIdentStart(src[i:]):
+			n := 0
+			for i+n < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i+n:])
+				if !(r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+					break
+				}
+				n += size
+			}
+			tokens = append(tokens, jsToken{kind: jsWord, text: src[i : i+n]})
+			i += n
+
+		default:
+			switch c {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case '(':
+				n := len(tokens)
+				parens = append(parens, n > 0 && tokens[n-1].kind == jsWord && conditionKeywords[tokens[n-1].text])
+			case ')':
+				afterCondition = false
+				if len(parens) > 0 {
+					afterCondition = parens[len(parens)-1]
+					parens = parens[:len(parens)-1]
+				}
+			}
+			_, size := utf8.DecodeRuneInString(src[i:])
+			tokens = append(tokens, jsToken{kind: jsPunct, text: src[i : i+size]})
+			i += size
+		}
+	}
+	return tokens
+}
+
+// isJSIdentStart reports whether an identifier, keyword or number starts
+// the source
+func isJSIdentStart(src string) bool {
+	r, _ := utf8.DecodeRuneInString(src)
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lexJSString reads a quoted string, returning its value and length. An
+// unterminated string ends at the line end, which keeps stray quotes, such
+// as apostrophes in JSX text, from swallowing the rest of the file.
+func lexJSString(src string) (string, int) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case quote:
+			return b.String(), i + 1
+		case '\n':
+			return b.String(), i
+		case '\\':
+			if i+1 < len(src) {
+				i++
+				b.WriteByte(src[i])
+			}
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String(), len(src)
+}
+
+// lexJSTemplate reads the rest of a template literal after a backtick or
+// a closing substitution brace. It returns the token, the length read and
+// whether it stopped at the start of a substitution.
+func lexJSTemplate(src string) (jsToken, int, bool) {
+	var b strings.Builder
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '`':
+			return jsToken{kind: jsTemplate, text: b.String()}, i + 1, false
+		case src[i] == '\\' && i+1 < len(src):
+			i++
+			b.WriteByte(src[i])
+		case src[i] == '$' && i+1 < len(src) && src[i+1] == '{':
+			// Only templates without substitutions name a fixed module
+			return jsToken{kind: jsTemplate}, i + 2, true
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return jsToken{kind: jsTemplate, text: b.String()}, len(src), false
+}
+
+// lexJSRegex returns the length of a regex literal, including its flags
+func lexJSRegex(src string) int {
+	inClass := false
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return i
+		case '/':
+			if !inClass {
+				i++
+				for i < len(src) && isJSIdentStart(src[i:]) {
+					i++
+				}
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+// regexAllowed reports whether a slash after the tokens starts a regex
+// literal rather than a division. afterCondition tells whether the last
+// closing parenthesis ended the head of an if, while, for or with.
+func regexAllowed(tokens []jsToken, afterCondition bool) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	switch last.kind {
+	case jsWord:
+		return regexKeywords[last.text]
+	case jsPunct:
+		if last.text == ")" {
+			return afterCondition
+		}
+		return last.text != "]" && last.text != "}"
+	default:
+		return false
+	}
+}
+
+// jsReference is a module specifier found in JS or TS source
+type jsReference struct {
+	spec string
+	kind string
+}
+
+// findJSReferences extracts every static and dynamic module reference:
+// imports, side-effect imports, type-only imports and exports, re-exports,
+// dynamic import() and require()
+func findJSReferences(tokens []jsToken) []jsReference {
+	var refs []jsReference
+	at := func(i int, kind jsTokenKind, text string) bool {
+		return i < len(tokens) && tokens[i].kind == kind && (text == "" || tokens[i].text == text)
+	}
+	isSpec := func(i int) bool {
+		return at(i, jsString, "") || (at(i, jsTemplate, "") && tokens[i].text != "")
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind != jsWord || (i > 0 && at(i-1, jsPunct, ".")) {
+			continue
+		}
+
+		switch tokens[i].text {
+		case "import":
+			switch {
+			case at(i+1, jsPunct, "(") && isSpec(i+2):
+				refs = append(refs, jsReference{tokens[i+2].text, EdgeDynamic})
+			case at(i+1, jsString, ""):
+				refs = append(refs, jsReference{tokens[i+1].text, EdgeSideEffect})
+			case at(i+1, jsPunct, "."), at(i+1, jsPunct, "("):
+				// import.meta, or import() of a computed specifier
+			default:
+				kind := EdgeImport
+				// "import type from" imports a default export named type
+				if at(i+1, jsWord, "type") && !(at(i+2, jsWord, "from") && at(i+3, jsString, "")) {
+					kind = EdgeType
+				}
+				if spec, ok := fromClause(tokens, i+1); ok {
+					refs = append(refs, jsReference{spec, kind})
+				}
+			}
+
+		case "export":
+			kind := EdgeReExport
+			j := i + 1
+			if at(j, jsWord, "type") {
+				kind = EdgeType
+				j++
+			}
+			if at(j, jsPunct, "*") || at(j, jsPunct, "{") {
+				if spec, ok := fromClause(tokens, j); ok {
+					refs = append(refs, jsReference{spec, kind})
+				}
+			}
+
+		case "require":
+			if at(i+1, jsPunct, "(") && isSpec(i+2) && at(i+3, jsPunct, ")") {
+				refs = append(refs, jsReference{tokens[i+2].text, EdgeRequire})
+			}
+		}
+	}
+	return refs
+}
+
+// fromClause finds the specifier of the from clause ending an import or
+// export statement that starts at tokens[i], skipping its braces
+func fromClause(tokens []jsToken, i int) (string, bool) {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == jsPunct {
+			switch t.text {
+			case "{":
+				depth++
+			case "}":
+				// A stray brace must not hide the from clause
+				if depth > 0 {
+					depth--
+				}
+			case ";", "=", "(":
+				// The statement ended, or is import x = require() or a
+				// declaration
+				if depth == 0 {
+					return "", false
+				}
+			}
+			continue
+		}
+		if depth == 0 && t.kind == jsWord && t.text == "from" && i+1 < len(tokens) && tokens[i+1].kind == jsString {
+			return tokens[i+1].text, true
+		}
+		if depth == 0 && t.kind == jsWord && (t.text == "import" || t.text == "export") {
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// regexTokens returns the text of the regex literals among the tokens
+func regexTokens(tokens []jsToken) []string {
+	var regexes []string
+	for _, t := range tokens {
+		if t.kind == jsRegex {
+			regexes = append(regexes, t.text)
+		}
+	}
+	return regexes
+}
+
+func TestLexJSRegex(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{name: "division", src: "a = b / c / d"},
+		{name: "division after call", src: "x = f(a) / 2 / g(b)"},
+		{name: "division after index", src: "x = a[0] / b[1] / 2"},
+		{name: "assignment", src: "re = /ab+c/gi", want: []string{"/ab+c/gi"}},
+		{name: "after return", src: "return /x/.test(s)", want: []string{"/x/"}},
+		{name: "after if condition", src: "if (x) /re/.test(y)", want: []string{"/re/"}},
+		{name: "after while condition", src: "while (m = f()) /a\\/b/g.exec(s)", want: []string{"/a\\/b/g"}},
+		{name: "nested parentheses in condition", src: "if (f(a) && (b)) /re/.test(c)", want: []string{"/re/"}},
+		{name: "division after grouping", src: "if (a) x = (b) / c / d"},
+		{name: "slash in class", src: "x = /[/]/", want: []string{"/[/]/"}},
+		{name: "in substitution", src: "s = `${/}/.source}`", want: []string{"/}/"}},
+		{name: "division after template", src: "n = `${a}` / 2 / b"},
+		{name: "division in substitution", src: "s = `${a / b / c}`"},
+		{name: "template in regex", src: "x = /`/; y = `/`", want: []string{"/`/"}},
+		{name: "nested template", src: "s = `${`${/a/}`}` / 2 / 3", want: []string{"/a/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regexTokens(lexJS(tt.src)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("regexes in %q = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindJSReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []jsReference
+	}{
+		{
+			name: "static imports",
+			src:  "import a from './a'\nimport { b, c as d } from \"./b\"\nimport * as e from './e'",
+			want: []jsReference{{"./a", EdgeImport}, {"./b", EdgeImport}, {"./e", EdgeImport}},
+		},
+		{
+			name: "side effect and type imports",
+			src:  "import './polyfill'\nimport type { T } from './types'\nimport type from './default'",
+			want: []jsReference{{"./polyfill", EdgeSideEffect}, {"./types", EdgeType}, {"./default", EdgeImport}},
+		},
+		{
+			name: "exports",
+			src:  "export * from './all'\nexport { x } from './x'\nexport type { T } from './t'\nexport const y = 1",
+			want: []jsReference{{"./all", EdgeReExport}, {"./x", EdgeReExport}, {"./t", EdgeType}},
+		},
+		{
+			name: "dynamic and require",
+			src:  "const a = require('./a')\nimport('./b')\nimport(`./c`)\nimport(`./${name}`)\nobj.require('./d')",
+			want: []jsReference{{"./a", EdgeRequire}, {"./b", EdgeDynamic}, {"./c", EdgeDynamic}},
+		},
+		{
+			name: "references in comments, strings and templates",
+			src:  "// import a from './a'\n/* require('./b') */\nconst s = \"import c from './c'\"\nconst t = `${x} import d from './d'`",
+		},
+		{
+			name: "reference after regex",
+			src:  "if (ok) /import x from '.\\/x'/.test(s)\nimport y from './y'",
+			want: []jsReference{{"./y", EdgeImport}},
+		},
+		{
+			name: "unbalanced braces",
+			src:  "export } from './stray'\nimport { a } } from './a'",
+			want: []jsReference{{"./a", EdgeImport}},
+		},
+		{
+			name: "import meta and equals require",
+			src:  "const u = import.meta.url\nimport fs = require('fs')",
+			want: []jsReference{{"fs", EdgeRequire}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findJSReferences(lexJS(tt.src))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("references = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pythonFrom matches a from import: its leading dots, module and names.
+// Dots and parentheses need no surrounding space, as in "from .import(a)".
+var pythonFrom = regexp.MustCompile(`^from\b([.\s]*)([\w.]*)\s*\bimport\b\s*(.+)$`)
+
+// pythonQuoted matches the strings of a TOML array or value
+var pythonQuoted = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// pyprojectPackageDir matches the root package entry of an inline
+// setuptools package-dir table
+var pyprojectPackageDir = regexp.MustCompile(`["']{2}\s*=\s*["']([^"']*)["']`)
+
+// pyprojectFrom matches the from key of a Poetry packages entry
+var pyprojectFrom = regexp.MustCompile(`from\s*=\s*["']([^"']*)["']`)
+
+// SetPythonRoots sets the absolute source roots absolute Python imports
+// resolve against, in search order
+func (a *DependencyAnalyzer) SetPythonRoots(roots []string) {
+	a.pythonRoots = roots
+}
+
+// analyzePython resolves the imports of a Python file to project modules.
+// Importing a package or module also runs the __init__.py of every package
+// on its path, so those are dependencies too. Imports found in no source
+// root, such as the standard library, are marked external.
+func (a *DependencyAnalyzer) analyzePython(filePath string) ([]string, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := make(map[string]bool)
+	var deps []string
+	add := func(dep string) {
+		if dep != "" && !dependencies[dep] {
+			dependencies[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+
+	for _, line := range pythonLogicalLines(string(src)) {
+		for _, stmt := range strings.Split(line, ";") {
+			stmt = strings.TrimSpace(stmt)
+
+			if rest, ok := strings.CutPrefix(stmt, "import "); ok {
+				for _, name := range pythonNames(rest) {
+					for _, dep := range a.resolvePython(filePath, 0, name) {
+						add(dep)
+					}
+				}
+				continue
+			}
+
+			m := pythonFrom.FindStringSubmatch(stmt)
+			if m == nil {
+				continue
+			}
+			level, module := strings.Count(m[1], "."), m[2]
+
+			// Each name may be a submodule, or a binding of the module
+			fromModule := false
+			for _, name := range pythonNames(m[3]) {
+				sub := name
+				if module != "" {
+					sub = module + "." + name
+				}
+				if file, _ := a.findPythonModule(filePath, level, sub); name != "*" && file != "" && file != pythonNamespace {
+					for _, dep := range a.resolvePython(filePath, level, sub) {
+						add(dep)
+					}
+					continue
+				}
+				fromModule = true
+			}
+			if fromModule {
+				for _, dep := range a.resolvePython(filePath, level, module) {
+					add(dep)
+				}
+			}
+		}
+	}
+
+	if deps == nil {
+		deps = []string{}
+	}
+	return deps, nil
+}
+
+// pythonNames returns the names imported by the rest of an import
+// statement, without aliases or parentheses
+func pythonNames(list string) []string {
+	list = strings.Trim(strings.TrimSpace(list), "()")
+	var names []string
+	for _, part := range strings.Split(list, ",") {
+		if fields := strings.Fields(part); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
+
+// resolvePython returns the file of a module and the __init__.py files of
+// the packages containing it, or the module marked external when it is
+// not in the project. level is the number of leading dots of a relative
+// import.
+func (a *DependencyAnalyzer) resolvePython(importer string, level int, module string) []string {
+	file, root := a.findPythonModule(importer, level, module)
+	if file == "" {
+		if level > 0 {
+			// A relative import of a module that does not exist yet
+			return nil
+		}
+		return []string{ExternalPrefix + module}
+	}
+	if file == pythonNamespace {
+		return nil
+	}
+
+	// The packages above the root of a relative import contain the importer
+	// and are imported with it; those below it are imported by this import
+	deps := []string{file}
+	parts := strings.Split(module, ".")
+	for i := 1; i < len(parts); i++ {
+		if init := filepath.Join(root, filepath.Join(parts[:i]...), "__init__.py"); isFile(init) {
+			deps = append(deps, init)
+		}
+	}
+	return deps
+}
+
+// pythonNamespace is returned for a namespace package, which is a
+// directory without __init__.py and so has no file to depend on
+const pythonNamespace = "namespace"
+
+// findPythonModule returns the file a module resolves to and the root it
+// was found in, pythonNamespace for a namespace package, or an empty string
+// if it is not found. Relative modules resolve against the importer's
+// package, absolute ones against the source roots and then the importer's
+// directory, as for a script.
+func (a *DependencyAnalyzer) findPythonModule(importer string, level int, module string) (string, string) {
+	var roots []string
+	if level > 0 {
+		dir := filepath.Dir(absPath(importer))
+		for i := 1; i < level; i++ {
+			dir = filepath.Dir(dir)
+		}
+		roots = []string{dir}
+	} else {
+		roots = append(append(roots, a.pythonRoots...), filepath.Dir(absPath(importer)))
+	}
+
+	var parts []string
+	if module != "" {
+		parts = strings.Split(module, ".")
+	}
+
+	namespace := false
+	for _, root := range roots {
+		base := filepath.Join(append([]string{root}, parts...)...)
+		if len(parts) > 0 && isFile(base+".py") {
+			return base + ".py", root
+		}
+		if init := filepath.Join(base, "__init__.py"); isFile(init) {
+			return init, root
+		}
+		if info, err := os.Stat(base); err == nil && info.IsDir() && len(parts) > 0 {
+			namespace = true
+		}
+	}
+	if namespace {
+		return pythonNamespace, ""
+	}
+	return "", ""
+}
+
+// pythonLogicalLines splits Python source into logical lines, joining
+// lines continued by brackets or backslashes. Comments are dropped and
+// strings, including triple-quoted ones, are replaced by empty strings so
+// their content never looks like an import.
+func pythonLogicalLines(src string) []string {
+	var lines []string
+	var b strings.Builder
+	depth := 0
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			i--
+
+		case c == '"' || c == '\'':
+			quote := src[i : i+1]
+			if strings.HasPrefix(src[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+			}
+			j := i + len(quote)
+			for j < len(src) && !strings.HasPrefix(src[j:], quote) {
+				if src[j] == '\\' {
+					j++
+				} else if src[j] == '\n' && len(quote) == 1 {
+					break
+				}
+				j++
+			}
+			b.WriteString(`""`)
+			i = min(j+len(quote), len(src)) - 1
+
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			b.WriteByte(' ')
+			i++
+
+		case c == '\n':
+			if depth > 0 {
+				b.WriteByte(' ')
+				continue
+			}
+			lines = append(lines, b.String())
+			b.Reset()
+
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			b.WriteByte(c)
+		}
+	}
+	return append(lines, b.String())
+}
+
+// PythonSourceRoots returns the absolute source roots of a project in
+// search order: the configured roots, PYTHONPATH, the roots declared in
+// pyproject.toml, src and the project directory itself
+func PythonSourceRoots(projectDir string, configured []string) []string {
+	projectDir = absPath(projectDir)
+	seen := make(map[string]bool)
+	var roots []string
+	add := func(root string) {
+		if root == "" {
+			return
+		}
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(projectDir, root)
+		}
+		if info, err := os.Stat(root); err == nil && info.IsDir() && !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+
+	for _, root := range configured {
+		add(root)
+	}
+	for _, root := range filepath.SplitList(os.Getenv("PYTHONPATH")) {
+		add(root)
+	}
+	for _, root := range pyprojectRoots(filepath.Join(projectDir, "pyproject.toml")) {
+		add(root)
+	}
+	add("src")
+	add(".")
+	return roots
+}
+
+// pyprojectRoots reads the source roots declared in pyproject.toml by
+// setuptools (package-dir and packages.find where), Poetry (packages
+// from), Hatch (wheel packages) and pytest (pythonpath). Only the simple
+// forms of these settings are understood.
+func pyprojectRoots(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var roots []string
+	table := ""
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "[") && !strings.Contains(line, "=") {
+			table = strings.Trim(line, "[] ")
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		value = strings.TrimSpace(value)
+
+		// Arrays may continue over several lines
+		for strings.HasPrefix(value, "[") && !strings.Contains(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(lines[i])
+		}
+
+		switch {
+		case table == "tool.setuptools.packages.find" && key == "where",
+			table == "tool.pytest.ini_options" && key == "pythonpath",
+			table == "tool.setuptools.package-dir" && key == "":
+			roots = append(roots, quotedStrings(value)...)
+		case table == "tool.setuptools" && key == "package-dir":
+			// An inline table mapping the root package to its directory
+			if m := pyprojectPackageDir.FindStringSubmatch(value); m != nil {
+				roots = append(roots, m[1])
+			}
+		case table == "tool.poetry" && key == "packages":
+			for _, m := range pyprojectFrom.FindAllStringSubmatch(value, -1) {
+				roots = append(roots, m[1])
+			}
+		case table == "tool.hatch.build.targets.wheel" && key == "packages":
+			// Each package names its directory, whose parent is the root
+			for _, pkg := range quotedStrings(value) {
+				roots = append(roots, filepath.Dir(pkg))
+			}
+		}
+	}
+	return roots
+}
+
+// quotedStrings returns the quoted strings in a TOML value
+func quotedStrings(value string) []string {
+	var result []string
+	for _, m := range pythonQuoted.FindAllStringSubmatch(value, -1) {
+		result = append(result, m[1]+m[2])
+	}
+	return result
+}
@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pythonProjectFiles is a package with a subpackage, a nested package and
+// a namespace package without __init__.py
+var pythonProjectFiles = map[string]string{
+	"pkg/__init__.py":        "",
+	"pkg/util.py":            "",
+	"pkg/sub/__init__.py":    "",
+	"pkg/sub/mod.py":         "",
+	"pkg/sub/other.py":       "",
+	"pkg/nested/__init__.py": "",
+	"pkg/nested/leaf.py":     "",
+	"pkg/ns/thing.py":        "",
+}
+
+func TestAnalyzePython(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{name: "sibling modules", src: "from . import(mod, other)", want: []string{"pkg/sub/mod.py", "pkg/sub/other.py"}},
+		{name: "no space before import", src: "from .import mod", want: []string{"pkg/sub/mod.py"}},
+		{name: "parent package", src: "from .. import(util, sub)", want: []string{"pkg/util.py", "pkg/sub/__init__.py"}},
+		{
+			name: "relative module in another package",
+			src:  "from ..nested.leaf import x",
+			want: []string{"pkg/nested/leaf.py", "pkg/nested/__init__.py"},
+		},
+		{
+			name: "relative submodule of another package",
+			src:  "from ..nested import leaf",
+			want: []string{"pkg/nested/leaf.py", "pkg/nested/__init__.py"},
+		},
+		{
+			name: "names over several lines",
+			src:  "from . import (\n    mod,  # the module\n    other as o,\n)",
+			want: []string{"pkg/sub/mod.py", "pkg/sub/other.py"},
+		},
+		{name: "binding of a module", src: "from .mod import helper", want: []string{"pkg/sub/mod.py"}},
+		{
+			name: "absolute import",
+			src:  "import pkg.nested.leaf",
+			want: []string{"pkg/nested/leaf.py", "pkg/__init__.py", "pkg/nested/__init__.py"},
+		},
+		{name: "namespace package", src: "from pkg.ns import thing", want: []string{"pkg/ns/thing.py", "pkg/__init__.py"}},
+		{name: "external", src: "import os, json as j; from typing import Any", want: []string{ExternalPrefix + "os", ExternalPrefix + "json", ExternalPrefix + "typing"}},
+		{name: "missing relative module", src: "from ..nested.missing import x", want: []string{}},
+		{name: "strings and comments", src: "s = \"from . import mod\"  # import pkg.util\n'''\nimport pkg.util\n'''", want: []string{}},
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, pythonProjectFiles)
+	a := NewDependencyAnalyzer()
+	a.SetPythonRoots([]string{dir})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importer := filepath.Join(dir, "pkg", "sub", "app.py")
+			writeFiles(t, dir, map[string]string{"pkg/sub/app.py": tt.src})
+
+			got, err := a.analyzePython(importer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]string, len(tt.want))
+			for i, dep := range tt.want {
+				if strings.HasPrefix(dep, ExternalPrefix) {
+					want[i] = dep
+				} else {
+					want[i] = filepath.Join(dir, filepath.FromSlash(dep))
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("dependencies = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPythonFrom(t *testing.T) {
+	tests := []struct {
+		stmt   string
+		dots   int
+		module string
+		names  string
+	}{
+		{stmt: "from pkg.mod import a", module: "pkg.mod", names: "a"},
+		{stmt: "from . import a, b", dots: 1, names: "a, b"},
+		{stmt: "from . import(a, b)", dots: 1, names: "(a, b)"},
+		{stmt: "from .import a", dots: 1, names: "a"},
+		{stmt: "from ..mod import*", dots: 2, module: "mod", names: "*"},
+		{stmt: "from .. import (a)", dots: 2, names: "(a)"},
+		{stmt: "from .importer import a", dots: 1, module: "importer", names: "a"},
+		{stmt: "from importlib import util", module: "importlib", names: "util"},
+		{stmt: "fromage import a", dots: -1},
+		{stmt: "from pkg importa", dots: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stmt, func(t *testing.T) {
+			m := pythonFrom.FindStringSubmatch(tt.stmt)
+			if tt.dots < 0 {
+				if m != nil {
+					t.Errorf("%q matched as a from import: %q", tt.stmt, m)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatalf("%q did not match", tt.stmt)
+			}
+			if dots := strings.Count(m[1], "."); dots != tt.dots || m[2] != tt.module || m[3] != tt.names {
+				t.Errorf("%q = %d dots, module %q, names %q, want %d, %q, %q", tt.stmt, dots, m[2], m[3], tt.dots, tt.module, tt.names)
+			}
+		})
+	}
+}
+
+func TestPyprojectRoots(t *testing.T) {
+	tests := []struct {
+		name      string
+		pyproject string
+		want      []string
+	}{
+		{name: "setuptools find", pyproject: "[tool.setuptools.packages.find]\nwhere = [\"src\", 'lib']\n", want: []string{"src", "lib"}},
+		{name: "setuptools package-dir table", pyproject: "[tool.setuptools.package-dir]\n\"\" = \"src\"\n", want: []string{"src"}},
+		{name: "setuptools inline package-dir", pyproject: "[tool.setuptools]\npackage-dir = {\"\" = \"src\"}\n", want: []string{"src"}},
+		{
+			name:      "poetry packages",
+			pyproject: "[tool.poetry]\npackages = [\n    { include = \"app\", from = \"src\" },\n    { include = \"tools\", from = \"lib\" },\n]\n",
+			want:      []string{"src", "lib"},
+		},
+		{name: "hatch wheel packages", pyproject: "[tool.hatch.build.targets.wheel]\npackages = [\"src/app\"]\n", want: []string{"src"}},
+		{name: "pytest pythonpath", pyproject: "[tool.pytest.ini_options]\npythonpath = [\".\", \"tests\"]\n", want: []string{".", "tests"}},
+		{name: "other tables", pyproject: "[project]\nname = \"app\"\n[tool.black]\nwhere = [\"src\"]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"pyproject.toml": tt.pyproject})
+			if got := pyprojectRoots(filepath.Join(dir, "pyproject.toml")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pyprojectRoots = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPythonSourceRoots(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pyproject.toml":  "[tool.setuptools.packages.find]\nwhere = [\"lib\", \"missing\"]\n",
+		"lib/a/a.py":      "",
+		"src/b/b.py":      "",
+		"vendored/c/c.py": "",
+	})
+	t.Setenv("PYTHONPATH", "")
+
+	got := PythonSourceRoots(dir, []string{"vendored", "lib"})
+	want := []string{filepath.Join(dir, "vendored"), filepath.Join(dir, "lib"), filepath.Join(dir, "src"), dir}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PythonSourceRoots = %v, want %v", got, want)
+	}
+}
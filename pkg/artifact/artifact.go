@@ -0,0 +1,220 @@
+package artifact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// manifestName is the file listing the retained builds in the store directory
+const manifestName = "manifest.json"
+
+// Build is a versioned build artifact
+type Build struct {
+	ID        int       `json:"id"`
+	Path      string    `json:"path"`
+	Trigger   string    `json:"trigger"`
+	CreatedAt time.Time `json:"createdAt"`
+	Good      bool      `json:"good"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+// Store keeps the last N build artifacts in numbered slots so a
+// previously working binary can be started again
+type Store struct {
+	mu     sync.Mutex
+	dir    string
+	keep   int
+	nextID int
+	builds []*Build
+}
+
+// NewStore opens the artifact store in dir, loading any retained builds.
+// At least two builds are kept: the new one and the last good one.
+func NewStore(dir string, keep int) (*Store, error) {
+	if keep < 2 {
+		keep = 2
+	}
+
+	// Builds run in the project directory, so slot paths must be absolute
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact dir: %w", err)
+	}
+
+	s := &Store{
+		dir:    dir,
+		keep:   keep,
+		nextID: 1,
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read artifact manifest: %w", err)
+	}
+	if err == nil {
+		var builds []*Build
+		if err := json.Unmarshal(data, &builds); err != nil {
+			return nil, fmt.Errorf("failed to parse artifact manifest: %w", err)
+		}
+		for _, b := range builds {
+			// Drop entries whose binary has been removed
+			if _, err := os.Stat(b.Path); err != nil {
+				continue
+			}
+			s.builds = append(s.builds, b)
+			if b.ID >= s.nextID {
+				s.nextID = b.ID + 1
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Reserve returns the ID and path of the slot for the next build
+func (s *Store) Reserve() (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	return id, filepath.Join(s.dir, fmt.Sprintf("build-%d", id))
+}
+
+// Commit records a successful build in its reserved slot and prunes old builds
+func (s *Store) Commit(id int, path, trigger string) *Build {
+	// Without a hash the build is never treated as identical to another
+	hash, _ := HashBinary(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &Build{
+		ID:        id,
+		Path:      path,
+		Trigger:   trigger,
+		CreatedAt: time.Now(),
+		Hash:      hash,
+	}
+	s.builds = append(s.builds, b)
+	s.prune()
+	s.save()
+	return b
+}
+
+// Discard removes the artifact of a reserved slot that was never committed
+func (s *Store) Discard(path string) {
+	os.Remove(path)
+}
+
+// Remove deletes a committed build and its artifact
+func (s *Store) Remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, b := range s.builds {
+		if b.ID == id {
+			os.Remove(b.Path)
+			s.builds = append(s.builds[:i], s.builds[i+1:]...)
+			s.save()
+			return
+		}
+	}
+}
+
+// MarkGood records that a build started and stayed up
+func (s *Store) MarkGood(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.builds {
+		if b.ID == id {
+			b.Good = true
+		}
+	}
+	s.save()
+}
+
+// Get returns the build with the given ID
+func (s *Store) Get(id int) (*Build, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.builds {
+		if b.ID == id {
+			copied := *b
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// LastGood returns the newest good build other than the excluded ID
+func (s *Store) LastGood(exclude int) (*Build, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.builds) - 1; i >= 0; i-- {
+		b := s.builds[i]
+		if b.Good && b.ID != exclude {
+			copied := *b
+			return &copied, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the retained builds, newest first
+func (s *Store) List() []Build {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	builds := make([]Build, 0, len(s.builds))
+	for _, b := range s.builds {
+		builds = append(builds, *b)
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].ID > builds[j].ID
+	})
+	return builds
+}
+
+// prune deletes the oldest builds beyond the retention limit.
+// The newest good build is always kept so there is something to roll back to.
+func (s *Store) prune() {
+	lastGood := -1
+	for i := len(s.builds) - 1; i >= 0; i-- {
+		if s.builds[i].Good {
+			lastGood = s.builds[i].ID
+			break
+		}
+	}
+
+	for len(s.builds) > s.keep {
+		victim := 0
+		if s.builds[0].ID == lastGood {
+			victim = 1
+		}
+		os.Remove(s.builds[victim].Path)
+		s.builds = append(s.builds[:victim], s.builds[victim+1:]...)
+	}
+}
+
+// save writes the manifest; failures only cost history across runs
+func (s *Store) save() {
+	data, err := json.MarshalIndent(s.builds, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, manifestName), data, 0644); err != nil {
+		fmt.Printf("⚠️  Failed to save artifact manifest: %v\n", err)
+	}
+}
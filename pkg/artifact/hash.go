@@ -0,0 +1,50 @@
+package artifact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"os"
+)
+
+// goBuildIDPrefix starts the build ID the Go linker writes into the text of
+// non-ELF binaries
+var goBuildIDPrefix = []byte("\xff Go build ID: \"")
+
+// HashBinary returns the SHA-256 of a binary with its build IDs removed.
+// The Go build ID, and the GNU build ID derived from it, change whenever a
+// source file does, even if only a comment changed and the code is the same.
+// A comment edit that adds or removes lines still changes the hash, since
+// the line table of the code after it moves.
+func HashBinary(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var id []byte
+	if f, err := elf.NewFile(bytes.NewReader(data)); err == nil {
+		if s := f.Section(".note.go.buildid"); s != nil {
+			// The note has a 16 byte header: sizes, type and the name "Go"
+			if note, err := s.Data(); err == nil && len(note) > 16 {
+				id = bytes.TrimRight(note[16:], "\x00")
+			}
+		}
+		if s := f.Section(".note.gnu.build-id"); s != nil && s.Offset+s.Size <= uint64(len(data)) {
+			clear(data[s.Offset : s.Offset+s.Size])
+		}
+	} else if i := bytes.Index(data, goBuildIDPrefix); i >= 0 {
+		rest := data[i+len(goBuildIDPrefix):]
+		if end := bytes.IndexByte(rest, '"'); end > 0 {
+			id = rest[:end]
+		}
+	}
+
+	if len(id) > 0 {
+		data = bytes.ReplaceAll(data, id, nil)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,84 @@
+package artifact
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const hashSource = `package main
+
+// greeting is printed on start
+var greeting = "hello"
+
+func main() {
+	println(greeting)
+}
+`
+
+// buildHash builds main.go with the given source and hashes the binary
+func buildHash(t *testing.T, dir, source string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "build", "-o", "app")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	hash, err := HashBinary(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashBinary(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		wantSame bool
+	}{
+		{name: "unchanged", source: hashSource, wantSame: true},
+		{
+			name:     "comment edited in place",
+			source:   strings.Replace(hashSource, "printed on start", "shown at startup", 1),
+			wantSame: true,
+		},
+		{
+			name:   "comment adds a line",
+			source: strings.Replace(hashSource, "// greeting", "// The greeting\n// greeting", 1),
+		},
+		{
+			name:   "code changed",
+			source: strings.Replace(hashSource, `"hello"`, `"hi"`, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			before := buildHash(t, dir, hashSource)
+			after := buildHash(t, dir, tt.source)
+			if same := before == after; same != tt.wantSame {
+				t.Errorf("hashes equal = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestHashBinaryMissing(t *testing.T) {
+	if _, err := HashBinary(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("HashBinary succeeded for a missing file")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"time"
 )
@@ -13,6 +14,15 @@ import (
 type ModuleCache struct {
 	mu      sync.RWMutex
 	entries map[string]*CacheEntry
+	resolve Resolver
+
+	// Composite keys computed from the files on disk, kept until a file
+	// they cover changes. deps and dependents record the resolved
+	// dependencies the keys were computed from.
+	keyMu      sync.Mutex
+	keys       map[string]string
+	deps       map[string][]string
+	dependents map[string]map[string]bool
 }
 
 // CacheEntry stores metadata about a cached file
@@ -21,12 +31,21 @@ type CacheEntry struct {
 	LastModified time.Time
 	Size         int64
 	Dependencies []string
+	// Key combines Hash with the keys of the resolved dependencies, so it
+	// changes when the file or anything it depends on changes
+	Key string
 }
 
+// Resolver returns the files a file depends on
+type Resolver func(path string) []string
+
 // NewModuleCache creates a new module cache
 func NewModuleCache() *ModuleCache {
 	return &ModuleCache{
-		entries: make(map[string]*CacheEntry),
+		entries:    make(map[string]*CacheEntry),
+		keys:       make(map[string]string),
+		deps:       make(map[string][]string),
+		dependents: make(map[string]map[string]bool),
 	}
 }
 
@@ -45,6 +64,15 @@ func (c *ModuleCache) Set(path string, entry *CacheEntry) {
 	c.entries[path] = entry
 }
 
+// SetResolver sets how a file's dependencies are resolved to files for its
+// composite key. Without a resolver the key covers only the file itself.
+func (c *ModuleCache) SetResolver(resolve Resolver) {
+	c.mu.Lock()
+	c.resolve = resolve
+	c.mu.Unlock()
+	c.forgetAll()
+}
+
 // Invalidate removes a cache entry
 func (c *ModuleCache) Invalidate(path string) {
 	c.mu.Lock()
@@ -52,31 +80,257 @@ func (c *ModuleCache) Invalidate(path string) {
 	delete(c.entries, path)
 }
 
+// Changed drops the computed keys a change to a file makes outdated: its
+// own and those of the files depending on it, directly or transitively. A
+// file whose dependencies were never resolved may be one that others can
+// now resolve to, so every key is dropped.
+func (c *ModuleCache) Changed(path string) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	c.forgetLocked(path)
+}
+
+// forgetLocked is Changed with keyMu held
+func (c *ModuleCache) forgetLocked(path string) {
+	if _, resolved := c.deps[path]; !resolved {
+		c.keys = make(map[string]string)
+		return
+	}
+
+	queue := []string{path}
+	seen := map[string]bool{path: true}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		delete(c.keys, file)
+		for dependent := range c.dependents[file] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+}
+
+// forgetAll drops every computed key
+func (c *ModuleCache) forgetAll() {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	c.keys = make(map[string]string)
+}
+
+// MissReason explains why a file's cache entry is not valid
+type MissReason string
+
+const (
+	// MissNotCached means the file has no cache entry
+	MissNotCached MissReason = "not cached"
+	// MissSize means the file size changed
+	MissSize MissReason = "size changed"
+	// MissHash means the content changed but not the size
+	MissHash MissReason = "content hash changed"
+	// MissModTime means only the modification time changed
+	MissModTime MissReason = "modification time changed"
+	// MissDependency means the file is unchanged but a file it depends on,
+	// directly or transitively, changed
+	MissDependency MissReason = "dependency changed"
+)
+
 // IsValid checks if a file's cache entry is still valid
 func (c *ModuleCache) IsValid(path string) (bool, error) {
+	reason, err := c.Check(path)
+	return reason == "", err
+}
+
+// Check compares a file with its cache entry and returns why the entry is
+// not valid, or an empty reason if it is
+func (c *ModuleCache) Check(path string) (MissReason, error) {
 	entry, exists := c.Get(path)
 	if !exists {
-		return false, nil
+		return MissNotCached, nil
 	}
 
 	// Check if file still exists and hasn't changed
 	info, err := os.Stat(path)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	// Compare modification time and size
-	if !info.ModTime().Equal(entry.LastModified) || info.Size() != entry.Size {
-		return false, nil
+	if info.Size() != entry.Size {
+		return MissSize, nil
 	}
 
 	// Compute current hash for verification
 	hash, err := ComputeFileHash(path)
 	if err != nil {
-		return false, err
+		return "", err
+	}
+	if hash != entry.Hash {
+		return MissHash, nil
+	}
+
+	// Touched without changing the content
+	if !info.ModTime().Equal(entry.LastModified) {
+		return MissModTime, nil
 	}
 
-	return hash == entry.Hash, nil
+	key, err := c.currentKey(path)
+	if err != nil {
+		return "", err
+	}
+	if key != entry.Key {
+		return MissDependency, nil
+	}
+	return "", nil
+}
+
+// Stale returns the cached files whose composite key no longer matches,
+// because they or a file they depend on changed
+func (c *ModuleCache) Stale() []string {
+	c.mu.RLock()
+	paths := make([]string, 0, len(c.entries))
+	for path := range c.entries {
+		paths = append(paths, path)
+	}
+	c.mu.RUnlock()
+	sort.Strings(paths)
+
+	stale := []string{}
+	for _, path := range paths {
+		entry, exists := c.Get(path)
+		if !exists {
+			continue
+		}
+		if key, err := c.currentKey(path); err != nil || key != entry.Key {
+			stale = append(stale, path)
+		}
+	}
+	return stale
+}
+
+// Refresh updates the entries of files that were rebuilt, keeping their
+// dependencies. Entries of files that no longer exist are removed.
+func (c *ModuleCache) Refresh(paths []string) {
+	for _, path := range paths {
+		entry, exists := c.Get(path)
+		if !exists {
+			continue
+		}
+		if err := c.UpdateCache(path, entry.Dependencies); err != nil {
+			c.Invalidate(path)
+		}
+	}
+}
+
+// currentKey computes the composite key of a file from the files on disk,
+// reusing the keys computed since the files they cover last changed
+func (c *ModuleCache) currentKey(path string) (string, error) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+
+	key, _, err := c.keyLocked(path, map[string]bool{})
+	return key, err
+}
+
+// keyLocked computes the composite key of a file with keyMu held. visiting
+// holds the files whose keys are being computed; a dependency cycle is
+// broken by leaving out the file that closes it. Such a key depends on
+// where the cycle was entered, so it is reported incomplete and not kept.
+func (c *ModuleCache) keyLocked(path string, visiting map[string]bool) (string, bool, error) {
+	if key, ok := c.keys[path]; ok {
+		return key, true, nil
+	}
+	if visiting[path] {
+		return "", false, nil
+	}
+	visiting[path] = true
+	defer delete(visiting, path)
+
+	hash, err := c.currentHash(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	depKeys, complete := c.dependencyKeysLocked(path, visiting)
+	key := ComputeKey(hash, depKeys)
+	if complete {
+		c.keys[path] = key
+	}
+	return key, complete, nil
+}
+
+// dependencyKeysLocked computes the composite keys of the files a file
+// depends on and reports whether they are all complete
+func (c *ModuleCache) dependencyKeysLocked(path string, visiting map[string]bool) ([]string, bool) {
+	c.mu.RLock()
+	resolve := c.resolve
+	c.mu.RUnlock()
+	if resolve == nil {
+		return nil, true
+	}
+
+	deps := resolve(path)
+	c.setDepsLocked(path, deps)
+
+	complete := true
+	var depKeys []string
+	for _, dep := range deps {
+		key, ok, err := c.keyLocked(dep, visiting)
+		switch {
+		case err != nil:
+			// A deleted dependency changes the key like an edited one
+			depKeys = append(depKeys, "missing:"+dep)
+		case !ok && key == "":
+			// The dependency closes a cycle
+			complete = false
+		default:
+			complete = complete && ok
+			depKeys = append(depKeys, key)
+		}
+	}
+	return depKeys, complete
+}
+
+// setDepsLocked records the resolved dependencies of a file, so a change
+// to one of them drops the file's key
+func (c *ModuleCache) setDepsLocked(path string, deps []string) {
+	for _, old := range c.deps[path] {
+		delete(c.dependents[old], path)
+	}
+	c.deps[path] = deps
+	for _, dep := range deps {
+		if c.dependents[dep] == nil {
+			c.dependents[dep] = make(map[string]bool)
+		}
+		c.dependents[dep][path] = true
+	}
+}
+
+// currentHash returns the content hash of a file, reusing the cached hash
+// while its size and modification time are unchanged
+func (c *ModuleCache) currentHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if entry, exists := c.Get(path); exists && info.Size() == entry.Size && info.ModTime().Equal(entry.LastModified) {
+		return entry.Hash, nil
+	}
+	return ComputeFileHash(path)
+}
+
+// ComputeKey combines a file's content hash with the keys of its
+// dependencies, in any order, into a composite key
+func ComputeKey(hash string, depKeys []string) string {
+	sorted := append([]string(nil), depKeys...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	io.WriteString(h, hash)
+	for _, key := range sorted {
+		io.WriteString(h, "\n"+key)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // ComputeFileHash computes SHA-256 hash of a file
@@ -107,11 +361,21 @@ func (c *ModuleCache) UpdateCache(path string, deps []string) error {
 		return err
 	}
 
+	// The dependencies may have changed with the file
+	c.keyMu.Lock()
+	c.forgetLocked(path)
+	key, _, err := c.keyLocked(path, map[string]bool{})
+	c.keyMu.Unlock()
+	if err != nil {
+		return err
+	}
+
 	entry := &CacheEntry{
 		Hash:         hash,
 		LastModified: info.ModTime(),
 		Size:         info.Size(),
 		Dependencies: deps,
+		Key:          key,
 	}
 
 	c.Set(path, entry)
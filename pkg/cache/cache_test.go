@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testFiles writes files into a temporary directory and returns their paths
+// by name
+func testFiles(t *testing.T, files map[string]string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make(map[string]string)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = path
+	}
+	return paths
+}
+
+// graphResolver resolves dependencies from a map of names and counts the
+// files resolved
+type graphResolver struct {
+	paths    map[string]string
+	graph    map[string][]string
+	resolved map[string]int
+}
+
+func newGraphResolver(paths map[string]string, graph map[string][]string) *graphResolver {
+	return &graphResolver{paths: paths, graph: graph, resolved: make(map[string]int)}
+}
+
+func (r *graphResolver) resolve(path string) []string {
+	for name, p := range r.paths {
+		if p != path {
+			continue
+		}
+		r.resolved[name]++
+		var deps []string
+		for _, dep := range r.graph[name] {
+			deps = append(deps, r.paths[dep])
+		}
+		return deps
+	}
+	return nil
+}
+
+// resolvedNames returns the sorted names of the files resolved since the
+// last call
+func (r *graphResolver) resolvedNames() []string {
+	var names []string
+	for name := range r.resolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r.resolved = make(map[string]int)
+	return names
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, paths map[string]string)
+		want   MissReason
+	}{
+		{name: "unchanged", change: func(*testing.T, map[string]string) {}},
+		{
+			name: "size",
+			change: func(t *testing.T, paths map[string]string) {
+				os.WriteFile(paths["a"], []byte("package a // edited\n"), 0644)
+			},
+			want: MissSize,
+		},
+		{
+			name: "hash",
+			change: func(t *testing.T, paths map[string]string) {
+				os.WriteFile(paths["a"], []byte("package A\n"), 0644)
+			},
+			want: MissHash,
+		},
+		{
+			name: "modification time",
+			change: func(t *testing.T, paths map[string]string) {
+				later := time.Now().Add(time.Hour)
+				os.Chtimes(paths["a"], later, later)
+			},
+			want: MissModTime,
+		},
+		{
+			name: "dependency",
+			change: func(t *testing.T, paths map[string]string) {
+				os.WriteFile(paths["b"], []byte("package b // edited\n"), 0644)
+			},
+			want: MissDependency,
+		},
+		{
+			name: "deleted dependency",
+			change: func(t *testing.T, paths map[string]string) {
+				os.Remove(paths["b"])
+			},
+			want: MissDependency,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := testFiles(t, map[string]string{"a": "package a\n", "b": "package b\n"})
+			r := newGraphResolver(paths, map[string][]string{"a": {"b"}})
+			c := NewModuleCache()
+			c.SetResolver(r.resolve)
+			for _, name := range []string{"b", "a"} {
+				if err := c.UpdateCache(paths[name], nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tt.change(t, paths)
+			c.Changed(paths["a"])
+			c.Changed(paths["b"])
+
+			got, err := c.Check(paths["a"])
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Check = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := NewModuleCache().Check("missing"); got != MissNotCached {
+		t.Errorf("Check of an uncached file = %q, want %q", got, MissNotCached)
+	}
+}
+
+func TestStaleRecomputesOnlyDependents(t *testing.T) {
+	// a -> b -> c, d -> c, e stands alone
+	files := map[string]string{"a": "a\n", "b": "b\n", "c": "c\n", "d": "d\n", "e": "e\n"}
+	graph := map[string][]string{"a": {"b"}, "b": {"c"}, "d": {"c"}}
+
+	tests := []struct {
+		name         string
+		changed      string
+		edit         bool
+		wantStale    []string
+		wantResolved []string
+	}{
+		{name: "leaf", changed: "c", edit: true, wantStale: []string{"a", "b", "c", "d"}, wantResolved: []string{"a", "b", "c", "d"}},
+		{name: "middle", changed: "b", edit: true, wantStale: []string{"a", "b"}, wantResolved: []string{"a", "b"}},
+		{name: "standalone", changed: "e", edit: true, wantStale: []string{"e"}, wantResolved: []string{"e"}},
+		{name: "touched without edit", changed: "b", wantResolved: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := testFiles(t, files)
+			r := newGraphResolver(paths, graph)
+			c := NewModuleCache()
+			c.SetResolver(r.resolve)
+			for name := range files {
+				if err := c.UpdateCache(paths[name], nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if stale := c.Stale(); len(stale) != 0 {
+				t.Fatalf("Stale before any change = %v", stale)
+			}
+			r.resolvedNames()
+
+			if tt.edit {
+				os.WriteFile(paths[tt.changed], []byte("edited\n"), 0644)
+			}
+			c.Changed(paths[tt.changed])
+
+			var stale []string
+			for _, path := range c.Stale() {
+				stale = append(stale, filepath.Base(path))
+			}
+			if !reflect.DeepEqual(stale, tt.wantStale) {
+				t.Errorf("Stale = %v, want %v", stale, tt.wantStale)
+			}
+			if got := r.resolvedNames(); !reflect.DeepEqual(got, tt.wantResolved) {
+				t.Errorf("resolved %v, want %v", got, tt.wantResolved)
+			}
+
+			// Nothing changed since, so the keys are reused
+			c.Stale()
+			if got := r.resolvedNames(); len(got) != 0 {
+				t.Errorf("second Stale resolved %v, want nothing", got)
+			}
+		})
+	}
+}
+
+func TestStaleWithCycle(t *testing.T) {
+	paths := testFiles(t, map[string]string{"a": "a\n", "b": "b\n"})
+	r := newGraphResolver(paths, map[string][]string{"a": {"b"}, "b": {"a"}})
+	c := NewModuleCache()
+	c.SetResolver(r.resolve)
+	for _, name := range []string{"a", "b"} {
+		if err := c.UpdateCache(paths[name], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keys of files in a cycle do not depend on the order they are computed
+	if stale := c.Stale(); len(stale) != 0 {
+		t.Fatalf("Stale before any change = %v", stale)
+	}
+
+	os.WriteFile(paths["b"], []byte("edited\n"), 0644)
+	c.Changed(paths["b"])
+	if stale := c.Stale(); !reflect.DeepEqual(stale, []string{paths["a"], paths["b"]}) {
+		t.Errorf("Stale = %v, want both files of the cycle", stale)
+	}
+}
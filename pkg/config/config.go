@@ -0,0 +1,549 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"hotreloader/pkg/process"
+)
+
+// Modes select what happens after a change
+const (
+	// ModeRun rebuilds and restarts the application
+	ModeRun = "run"
+	// ModeTest runs the tests of the affected Go packages
+	ModeTest = "test"
+)
+
+// FileName is the name of the config file looked up in the project directory
+const FileName = ".hotreloader.json"
+
+// StateDirName is the directory in the project where the reloader keeps its state
+const StateDirName = ".hotreloader"
+
+// Config holds the user configurable settings of the hot reloader
+type Config struct {
+	// Mode is ModeRun (the default) or ModeTest
+	Mode   string       `json:"mode"`
+	Test   TestConfig   `json:"test"`
+	Go     GoConfig     `json:"go"`
+	Python PythonConfig `json:"python"`
+
+	Hooks     HooksConfig     `json:"hooks"`
+	Readiness []ProbeConfig   `json:"readiness"`
+	Artifacts ArtifactsConfig `json:"artifacts"`
+	Restart   RestartConfig   `json:"restart"`
+
+	// StopSequence lists the signals and waits used to stop the
+	// application, for example "SIGTERM, wait 10s, SIGKILL"
+	StopSequence string `json:"stopSequence"`
+
+	// Listeners are sockets the reloader opens and passes to the
+	// application using the systemd LISTEN_FDS convention
+	Listeners []string `json:"listeners"`
+
+	Proxy      ProxyConfig      `json:"proxy"`
+	LiveReload LiveReloadConfig `json:"liveReload"`
+	Output     OutputConfig     `json:"output"`
+
+	// Processes replace the single application with a set of named
+	// long-running commands, merged with the project's Procfile
+	Processes []ProcessConfig `json:"processes"`
+
+	// Pipeline replaces the single build step with a set of stages
+	Pipeline []StageConfig `json:"pipeline"`
+
+	// Rules handle changes to matching files with their own actions
+	// instead of the build
+	Rules []RuleConfig `json:"rules"`
+
+	// RestartStrategies select how a change is taken up after the build
+	RestartStrategies []StrategyConfig `json:"restartStrategies"`
+}
+
+// HooksConfig lists the commands to run at each lifecycle stage
+type HooksConfig struct {
+	PreBuild       []HookConfig `json:"pre-build"`
+	PostBuild      []HookConfig `json:"post-build"`
+	OnBuildFailure []HookConfig `json:"on-build-failure"`
+	PreRestart     []HookConfig `json:"pre-restart"`
+	PostRestart    []HookConfig `json:"post-restart"`
+	OnShutdown     []HookConfig `json:"on-shutdown"`
+}
+
+// HookConfig describes a single hook command
+type HookConfig struct {
+	Command      string   `json:"command"`
+	Timeout      Duration `json:"timeout"`
+	AllowFailure bool     `json:"allowFailure"`
+}
+
+// ProbeConfig describes a readiness probe run after each process start.
+// Type is "http" (Target is a URL that must answer 2xx), "tcp" (Target is
+// a host:port that must accept connections) or "log" (Target is a regular
+// expression matched against the process output).
+type ProbeConfig struct {
+	Type     string   `json:"type"`
+	Target   string   `json:"target"`
+	Timeout  Duration `json:"timeout"`
+	Interval Duration `json:"interval"`
+}
+
+// ArtifactsConfig controls build retention and automatic rollback
+type ArtifactsConfig struct {
+	Keep         int      `json:"keep"`
+	GracePeriod  Duration `json:"gracePeriod"`
+	AutoRollback bool     `json:"autoRollback"`
+}
+
+// RestartConfig controls what happens when the application exits on its own.
+// Policy is "never", "on-failure" or "always". After MaxFailures exits
+// within Window the process is left stopped until the next change.
+type RestartConfig struct {
+	Policy      string   `json:"policy"`
+	MaxFailures int      `json:"maxFailures"`
+	Window      Duration `json:"window"`
+	Backoff     Duration `json:"backoff"`
+	MaxBackoff  Duration `json:"maxBackoff"`
+}
+
+// TestConfig controls the test mode. Parallel is the number of packages
+// tested at once (default GOMAXPROCS), Args are extra `go test` flags and
+// Timeout bounds a whole run. Coverage enables selecting tests by the
+// files they cover, from per-test coverage collected in the background.
+type TestConfig struct {
+	Parallel int      `json:"parallel"`
+	Args     []string `json:"args"`
+	Timeout  Duration `json:"timeout"`
+	Coverage bool     `json:"coverage"`
+}
+
+// GoConfig selects the target Go files are analyzed for. GOOS and GOARCH
+// default to the environment, then to the host; Tags are extra build tags,
+// which builds use too.
+type GoConfig struct {
+	GOOS   string   `json:"goos"`
+	GOARCH string   `json:"goarch"`
+	Tags   []string `json:"tags"`
+}
+
+// PythonConfig lists the source roots Python imports resolve against,
+// relative to the project directory. They are searched before the roots
+// found in PYTHONPATH and pyproject.toml, then src and the project itself.
+type PythonConfig struct {
+	SourceRoots []string `json:"sourceRoots"`
+}
+
+// ProcessConfig describes a named long-running process. Command runs
+// through the shell in the project directory. The process is restarted when
+// a changed or affected file matches one of Patterns; without patterns it is
+// restarted on every change.
+type ProcessConfig struct {
+	Name      string        `json:"name"`
+	Command   string        `json:"command"`
+	Patterns  []string      `json:"patterns"`
+	Readiness []ProbeConfig `json:"readiness"`
+}
+
+// Stage types of a pipeline
+const (
+	// StageCommand runs a shell command
+	StageCommand = "command"
+	// StageBuild runs the active build plugin
+	StageBuild = "build"
+	// StageRestart restarts the application or the affected processes
+	StageRestart = "restart"
+)
+
+// StageConfig describes a pipeline stage. Type is StageCommand (the
+// default), StageBuild or StageRestart. A stage starts once the stages it
+// runs After have finished; stages that do not depend on each other run in
+// parallel. It is skipped unless the changed file matches one of Patterns.
+// A failing stage stops the pipeline unless it is Advisory.
+type StageConfig struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Command  string   `json:"command"`
+	Patterns []string `json:"patterns"`
+	After    []string `json:"after"`
+	Advisory bool     `json:"advisory"`
+	Timeout  Duration `json:"timeout"`
+}
+
+// RuleConfig maps changed files to actions. The first rule whose Patterns
+// match a changed file handles it: Command runs first, then Signal is sent
+// to the application, then a browser Reload is requested. The change only
+// goes on to the build when Build is set.
+type RuleConfig struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+	Command  string   `json:"command"`
+	Timeout  Duration `json:"timeout"`
+	Signal   string   `json:"signal"`
+	Reload   bool     `json:"reload"`
+	Build    bool     `json:"build"`
+}
+
+// Restart strategies
+const (
+	// StrategyRestart stops the process and starts the new build
+	StrategyRestart = "restart"
+	// StrategySignal sends Signal to the running process
+	StrategySignal = "signal"
+	// StrategyNone leaves the running process alone
+	StrategyNone = "none"
+	// StrategyOverlap starts the new build before stopping the old one
+	StrategyOverlap = "overlap"
+)
+
+// StrategyConfig selects the restart strategy for changes to files matching
+// Patterns. The first matching entry applies; without one the process is
+// restarted.
+type StrategyConfig struct {
+	Patterns []string `json:"patterns"`
+	Strategy string   `json:"strategy"`
+	Signal   string   `json:"signal"`
+}
+
+// ProxyConfig enables a reverse proxy on Listen that forwards to the
+// application at Target. Requests arriving during a build or restart are
+// held for up to HoldTimeout until the application is ready again.
+type ProxyConfig struct {
+	Listen      string   `json:"listen"`
+	Target      string   `json:"target"`
+	HoldTimeout Duration `json:"holdTimeout"`
+
+	// Process names the process behind Target when several are managed
+	Process string `json:"process"`
+}
+
+// LiveReloadConfig enables browser live reload. The client script is
+// injected into pages served through the proxy. Listen serves the endpoints
+// standalone as well; it defaults to ":35729" when no proxy is configured.
+type LiveReloadConfig struct {
+	Enabled bool   `json:"enabled"`
+	Listen  string `json:"listen"`
+}
+
+// OutputConfig controls how application output is shown and kept.
+// BufferLines is the number of recent lines kept in memory for the
+// dashboard and crash reports.
+type OutputConfig struct {
+	Timestamps  bool       `json:"timestamps"`
+	NoColor     bool       `json:"noColor"`
+	BufferLines int        `json:"bufferLines"`
+	Logs        LogsConfig `json:"logs"`
+}
+
+// LogsConfig enables log files under .hotreloader/logs, rotated once they
+// reach MaxSizeMB with MaxFiles rotated files kept
+type LogsConfig struct {
+	Enabled   bool `json:"enabled"`
+	MaxSizeMB int  `json:"maxSizeMB"`
+	MaxFiles  int  `json:"maxFiles"`
+}
+
+// Duration is a time.Duration that reads from JSON strings like "10s"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d.Duration = parsed
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	d.Duration = time.Duration(n)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		StopSequence: process.DefaultStopSequence,
+		Artifacts: ArtifactsConfig{
+			Keep:         5,
+			GracePeriod:  Duration{3 * time.Second},
+			AutoRollback: true,
+		},
+	}
+}
+
+// StateDir returns the reloader state directory of a project
+func StateDir(projectDir string) string {
+	return filepath.Join(projectDir, StateDirName)
+}
+
+// BuildsDir returns the directory holding retained build artifacts
+func BuildsDir(projectDir string) string {
+	return filepath.Join(StateDir(projectDir), "builds")
+}
+
+// CacheDir returns the directory holding persisted cache data
+func CacheDir(projectDir string) string {
+	return filepath.Join(StateDir(projectDir), "cache")
+}
+
+// LogsDir returns the directory holding application log files
+func LogsDir(projectDir string) string {
+	return filepath.Join(StateDir(projectDir), "logs")
+}
+
+// Load reads the config file from the project directory.
+// A missing file is not an error and yields the default configuration.
+func Load(projectDir string) (*Config, error) {
+	path := filepath.Join(projectDir, FileName)
+	cfg, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Config entries may only add patterns to Procfile processes
+	procs, err := LoadProcfile(filepath.Join(projectDir, ProcfileName))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Processes = MergeProcesses(cfg.Processes, procs)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// readFile parses the config file over the defaults without validating it
+func readFile(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// validateProbe checks the type and target of a readiness probe
+func validateProbe(probe ProbeConfig) error {
+	switch probe.Type {
+	case "http", "tcp":
+		if probe.Target == "" {
+			return fmt.Errorf("readiness probe %q needs a target", probe.Type)
+		}
+	case "log":
+		if _, err := regexp.Compile(probe.Target); err != nil {
+			return fmt.Errorf("readiness log pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown readiness probe type %q", probe.Type)
+	}
+	return nil
+}
+
+// Validate checks the settings that cannot be verified by the JSON decoder
+func (c *Config) Validate() error {
+	for _, probe := range c.Readiness {
+		if err := validateProbe(probe); err != nil {
+			return err
+		}
+	}
+
+	if _, err := process.ParseStopSequence(c.StopSequence); err != nil {
+		return fmt.Errorf("stopSequence: %w", err)
+	}
+
+	switch c.Mode {
+	case "", ModeRun, ModeTest:
+	default:
+		return fmt.Errorf("unknown mode %q", c.Mode)
+	}
+
+	switch c.Restart.Policy {
+	case "", "never", "on-failure", "always":
+	default:
+		return fmt.Errorf("unknown restart policy %q", c.Restart.Policy)
+	}
+
+	if c.Proxy.Listen != "" && c.Proxy.Target == "" {
+		return fmt.Errorf("proxy needs a target")
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range c.Processes {
+		if p.Name == "" {
+			return fmt.Errorf("process without a name")
+		}
+		// The name is used as the name of the process's log file
+		if strings.ContainsAny(p.Name, `/\`) || p.Name == "." || p.Name == ".." {
+			return fmt.Errorf("invalid process name %q: must not be a path", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate process %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		if p.Command == "" {
+			return fmt.Errorf("process %q needs a command", p.Name)
+		}
+		for _, probe := range p.Readiness {
+			if err := validateProbe(probe); err != nil {
+				return fmt.Errorf("process %q: %w", p.Name, err)
+			}
+		}
+	}
+
+	for i, r := range c.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(r.Patterns) == 0 {
+			return fmt.Errorf("rule %s needs patterns", name)
+		}
+		if r.Command == "" && r.Signal == "" && !r.Reload && !r.Build {
+			return fmt.Errorf("rule %s has no action", name)
+		}
+		if r.Signal != "" {
+			if _, _, err := process.ParseSignal(r.Signal); err != nil {
+				return fmt.Errorf("rule %s: %w", name, err)
+			}
+		}
+	}
+
+	for _, s := range c.RestartStrategies {
+		if len(s.Patterns) == 0 {
+			return fmt.Errorf("restart strategy %q needs patterns", s.Strategy)
+		}
+		switch s.Strategy {
+		case StrategyRestart, StrategyNone, StrategyOverlap:
+		case StrategySignal:
+			if _, _, err := process.ParseSignal(s.Signal); err != nil {
+				return fmt.Errorf("restart strategy signal: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown restart strategy %q", s.Strategy)
+		}
+	}
+
+	return c.validatePipeline()
+}
+
+// validatePipeline checks stage names, types and that the After references
+// form no cycle
+func (c *Config) validatePipeline() error {
+	stages := make(map[string]StageConfig)
+	builtins := make(map[string]bool)
+	for _, s := range c.Pipeline {
+		if s.Name == "" {
+			return fmt.Errorf("pipeline stage without a name")
+		}
+		if _, ok := stages[s.Name]; ok {
+			return fmt.Errorf("duplicate pipeline stage %q", s.Name)
+		}
+		stages[s.Name] = s
+
+		switch s.Type {
+		case "", StageCommand:
+			if s.Command == "" {
+				return fmt.Errorf("pipeline stage %q needs a command", s.Name)
+			}
+		case StageBuild, StageRestart:
+			if builtins[s.Type] {
+				return fmt.Errorf("pipeline has more than one %s stage", s.Type)
+			}
+			builtins[s.Type] = true
+		default:
+			return fmt.Errorf("pipeline stage %q: unknown type %q", s.Name, s.Type)
+		}
+	}
+
+	for _, s := range c.Pipeline {
+		for _, dep := range s.After {
+			if _, ok := stages[dep]; !ok {
+				return fmt.Errorf("pipeline stage %q runs after unknown stage %q", s.Name, dep)
+			}
+		}
+	}
+
+	// Depth-first search for a stage reachable from itself
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("pipeline stage %q depends on itself", name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range stages[name].After {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	for _, s := range c.Pipeline {
+		if err := visit(s.Name); err != nil {
+			return err
+		}
+	}
+
+	// The restart stage starts the output of the build stage
+	var restart StageConfig
+	for _, s := range c.Pipeline {
+		if s.Type == StageRestart {
+			restart = s
+		}
+	}
+	if builtins[StageBuild] && builtins[StageRestart] && !dependsOn(stages, restart.Name, StageBuild) {
+		return fmt.Errorf("pipeline stage %q must run after the build stage", restart.Name)
+	}
+
+	return nil
+}
+
+// dependsOn reports whether a stage runs after a stage of the given type,
+// directly or transitively
+func dependsOn(stages map[string]StageConfig, name, stageType string) bool {
+	for _, dep := range stages[name].After {
+		if stages[dep].Type == stageType || dependsOn(stages, dep, stageType) {
+			return true
+		}
+	}
+	return false
+}
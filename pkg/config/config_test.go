@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   ProbeConfig
+		wantErr string
+	}{
+		{name: "http", probe: ProbeConfig{Type: "http", Target: "http://localhost:8080/health"}},
+		{name: "log", probe: ProbeConfig{Type: "log", Target: "^listening"}},
+		{name: "missing target", probe: ProbeConfig{Type: "tcp"}, wantErr: `readiness probe "tcp" needs a target`},
+		{name: "bad pattern", probe: ProbeConfig{Type: "log", Target: "("}, wantErr: "readiness log pattern"},
+		{name: "unknown type", probe: ProbeConfig{Type: "udp", Target: ":53"}, wantErr: `unknown readiness probe type "udp"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Top-level and per-process probes are checked alike
+			top := Default()
+			top.Readiness = []ProbeConfig{tt.probe}
+			proc := Default()
+			proc.Processes = []ProcessConfig{{Name: "web", Command: "serve", Readiness: []ProbeConfig{tt.probe}}}
+
+			for _, c := range []*Config{top, proc} {
+				err := c.Validate()
+				switch {
+				case tt.wantErr == "" && err != nil:
+					t.Errorf("Validate: %v", err)
+				case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+					t.Errorf("Validate error = %v, want %q", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMergesProcfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		procfile string
+		want     map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "procfile only",
+			procfile: "web: ./server\nworker: ./worker\n",
+			want:     map[string]string{"web": "./server", "worker": "./worker"},
+		},
+		{
+			name:     "config adds patterns to a procfile entry",
+			config:   `{"processes": [{"name": "web", "patterns": ["*.go"]}]}`,
+			procfile: "web: ./server\n",
+			want:     map[string]string{"web": "./server"},
+		},
+		{
+			name:     "merged processes are validated",
+			config:   `{"processes": [{"name": "web", "readiness": [{"type": "log", "target": "("}]}]}`,
+			procfile: "web: ./server\n",
+			wantErr:  `process "web": readiness log pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.config != "" {
+				os.WriteFile(filepath.Join(dir, FileName), []byte(tt.config), 0644)
+			}
+			os.WriteFile(filepath.Join(dir, ProcfileName), []byte(tt.procfile), 0644)
+
+			cfg, err := Load(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, p := range cfg.Processes {
+				got[p.Name] = p.Command
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("processes = %v, want %v", got, tt.want)
+			}
+			for name, command := range tt.want {
+				if got[name] != command {
+					t.Errorf("process %s command = %q, want %q", name, got[name], command)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateProcessNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{name: "web"},
+		{name: "web.v2"},
+		{name: "a..b"},
+		{name: "", wantErr: "process without a name"},
+		{name: "../web", wantErr: `invalid process name "../web"`},
+		{name: "logs/web", wantErr: `invalid process name "logs/web"`},
+		{name: `logs\web`, wantErr: "invalid process name"},
+		{name: "..", wantErr: `invalid process name ".."`},
+		{name: ".", wantErr: `invalid process name "."`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Processes = []ProcessConfig{{Name: tt.name, Command: "serve"}}
+			err := c.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ProcfileName is the Procfile looked up in the project directory
+const ProcfileName = "Procfile"
+
+// LoadProcfile reads "name: command" lines from a Procfile. Blank lines and
+// lines starting with # are skipped. A missing file yields no processes.
+func LoadProcfile(path string) ([]ProcessConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read Procfile: %w", err)
+	}
+	defer f.Close()
+
+	var procs []ProcessConfig
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, command, ok := strings.Cut(line, ":")
+		name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+		if !ok || name == "" || command == "" {
+			return nil, fmt.Errorf("Procfile line %d: expected \"name: command\"", n)
+		}
+
+		procs = append(procs, ProcessConfig{Name: name, Command: command})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Procfile: %w", err)
+	}
+	return procs, nil
+}
+
+// MergeProcesses adds Procfile processes to the configured ones. A configured
+// process with the same name takes its command from the Procfile when it
+// does not set one, so the config can add patterns to Procfile entries.
+func MergeProcesses(configured, procfile []ProcessConfig) []ProcessConfig {
+	merged := append([]ProcessConfig(nil), configured...)
+
+	for _, p := range procfile {
+		found := false
+		for i := range merged {
+			if merged[i].Name == p.Name {
+				if merged[i].Command == "" {
+					merged[i].Command = p.Command
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, p)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,231 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hotreloader/pkg/artifact"
+	"hotreloader/pkg/events"
+)
+
+// SocketName is the control socket created in the state directory
+const SocketName = "control.sock"
+
+// Backend is the part of the optimizer exposed over the control API
+type Backend interface {
+	Builds() []artifact.Build
+	Rollback(id int) error
+	Explanations() []events.Explanation
+}
+
+// Server serves the control API on a unix socket so CLI commands can
+// talk to a running hot reloader
+type Server struct {
+	path     string
+	backend  Backend
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer creates a control server listening on the socket in stateDir
+func NewServer(stateDir string, backend Backend) (*Server, error) {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state dir: %w", err)
+	}
+
+	path := filepath.Join(stateDir, SocketName)
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+
+	s := &Server{
+		path:     path,
+		backend:  backend,
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/builds", s.handleBuilds)
+	mux.HandleFunc("/rollback", s.handleRollback)
+	mux.HandleFunc("/explain", s.handleExplain)
+	s.server = &http.Server{Handler: mux}
+
+	return s, nil
+}
+
+// removeStaleSocket removes a socket left behind by a crashed run, which
+// would make Listen fail. A socket another instance still accepts
+// connections on is left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use by another hot reloader", path)
+	}
+	return os.Remove(path)
+}
+
+// Start serves requests in the background
+func (s *Server) Start() {
+	go s.server.Serve(s.listener)
+}
+
+// Close stops the server and removes the socket
+func (s *Server) Close() error {
+	err := s.server.Close()
+	os.Remove(s.path)
+	return err
+}
+
+// handleBuilds lists the retained builds
+func (s *Server) handleBuilds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.backend.Builds())
+}
+
+// handleRollback restarts the application from an earlier build
+func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "invalid build id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.backend.Rollback(id); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExplain lists why recent rebuilds happened, newest first. The file
+// parameter keeps only rebuilds triggered by that path or a path ending in
+// it after a separator.
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	file := r.URL.Query().Get("file")
+
+	explanations := []events.Explanation{}
+	for _, e := range s.backend.Explanations() {
+		if file == "" || triggeredBy(e.Trigger, file) {
+			explanations = append(explanations, e)
+		}
+	}
+	writeJSON(w, explanations)
+}
+
+// triggeredBy reports whether a slash separated trigger path names file,
+// so that "a.go" matches "pkg/a.go" but not "data.go"
+func triggeredBy(trigger, file string) bool {
+	file = strings.TrimPrefix(path.Clean(file), "./")
+	return trigger == file || strings.HasSuffix(trigger, "/"+file)
+}
+
+// writeJSON encodes v as the response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Client talks to the control API of a running hot reloader
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the control socket in stateDir
+func NewClient(stateDir string) *Client {
+	path := filepath.Join(stateDir, SocketName)
+	return &Client{
+		http: &http.Client{
+			Timeout: 2 * time.Minute,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// Builds returns the builds retained by the running hot reloader
+func (c *Client) Builds() ([]artifact.Build, error) {
+	resp, err := c.http.Get("http://hotreloader/builds")
+	if err != nil {
+		return nil, fmt.Errorf("hot reloader not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var builds []artifact.Build
+	if err := json.NewDecoder(resp.Body).Decode(&builds); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return builds, nil
+}
+
+// Rollback asks the running hot reloader to start an earlier build
+func (c *Client) Rollback(id int) error {
+	resp, err := c.http.Post("http://hotreloader/rollback?id="+strconv.Itoa(id), "", nil)
+	if err != nil {
+		return fmt.Errorf("hot reloader not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkStatus(resp)
+}
+
+// Explanations returns why recent rebuilds happened, newest first,
+// optionally only those triggered by file
+func (c *Client) Explanations(file string) ([]events.Explanation, error) {
+	resp, err := c.http.Get("http://hotreloader/explain?file=" + url.QueryEscape(file))
+	if err != nil {
+		return nil, fmt.Errorf("hot reloader not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var explanations []events.Explanation
+	if err := json.NewDecoder(resp.Body).Decode(&explanations); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return explanations, nil
+}
+
+// checkStatus turns non-2xx responses into errors
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("%s: %s", resp.Status, string(body))
+}
@@ -0,0 +1,146 @@
+package control
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hotreloader/pkg/artifact"
+	"hotreloader/pkg/events"
+)
+
+// testBackend serves fixed explanations
+type testBackend struct {
+	explanations []events.Explanation
+}
+
+func (b *testBackend) Builds() []artifact.Build           { return nil }
+func (b *testBackend) Rollback(id int) error              { return nil }
+func (b *testBackend) Explanations() []events.Explanation { return b.explanations }
+
+func TestHandleExplain(t *testing.T) {
+	backend := &testBackend{explanations: []events.Explanation{
+		{Trigger: "data.go"},
+		{Trigger: "a.go"},
+		{Trigger: "pkg/a.go"},
+		{Trigger: "pkg/metadata.go"},
+		{Trigger: "/outside/a.go"},
+	}}
+	s := &Server{backend: backend}
+
+	tests := []struct {
+		name string
+		file string
+		want []string
+	}{
+		{name: "all", want: []string{"data.go", "a.go", "pkg/a.go", "pkg/metadata.go", "/outside/a.go"}},
+		{name: "base name", file: "a.go", want: []string{"a.go", "pkg/a.go", "/outside/a.go"}},
+		{name: "relative path", file: "pkg/a.go", want: []string{"pkg/a.go"}},
+		{name: "dot prefix", file: "./data.go", want: []string{"data.go"}},
+		{name: "partial directory name", file: "kg/a.go"},
+		{name: "absolute path", file: "/outside/a.go", want: []string{"/outside/a.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleExplain(rec, httptest.NewRequest("GET", "/explain?file="+url.QueryEscape(tt.file), nil))
+
+			var explanations []events.Explanation
+			if err := json.NewDecoder(rec.Body).Decode(&explanations); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range explanations {
+				got = append(got, e.Trigger)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("triggers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewServerSocket(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, path string)
+		wantErr string
+	}{
+		{name: "no socket"},
+		{
+			name: "stale socket",
+			setup: func(t *testing.T, path string) {
+				l, err := net.Listen("unix", path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// A crashed run leaves its socket behind
+				l.(*net.UnixListener).SetUnlinkOnClose(false)
+				l.Close()
+			},
+		},
+		{
+			name: "socket of a running instance",
+			setup: func(t *testing.T, path string) {
+				s, err := NewServer(filepath.Dir(path), &testBackend{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Start()
+				t.Cleanup(func() { s.Close() })
+			},
+			wantErr: "in use by another hot reloader",
+		},
+		{
+			name: "file in the way",
+			setup: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "failed to listen on control socket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Socket paths are limited to about 100 bytes
+			dir, err := os.MkdirTemp("", "ctl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.RemoveAll(dir) })
+			path := filepath.Join(dir, SocketName)
+			if tt.setup != nil {
+				tt.setup(t, path)
+			}
+
+			s, err := NewServer(dir, &testBackend{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewServer error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Lstat(path); err != nil {
+					t.Errorf("existing %s removed: %v", SocketName, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewServer: %v", err)
+			}
+			s.Start()
+			defer s.Close()
+
+			if _, err := NewClient(dir).Builds(); err != nil {
+				t.Errorf("Builds through the new socket: %v", err)
+			}
+		})
+	}
+}
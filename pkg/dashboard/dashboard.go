@@ -5,17 +5,33 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"hotreloader/pkg/events"
 )
 
 // Dashboard displays real-time rebuild metrics
 type Dashboard struct {
-	mu              sync.RWMutex
-	events          []Event
-	maxEvents       int
-	lastUpdate      time.Time
-	totalCacheHits  int
-	totalRebuilds   int
-	totalAffected   int
+	mu                sync.RWMutex
+	events            []Event
+	maxEvents         int
+	lastUpdate        time.Time
+	totalCacheHits    int
+	totalRebuilds     int
+	totalAffected     int
+	totalReady        int
+	totalFailedStarts int
+	totalCrashes      int
+	totalTestRuns     int
+	totalTestsFailed  int
+	totalPipelines    int
+	totalNoOpBuilds   int
+	totalPipelineErrs int
+	output            OutputSource
+}
+
+// OutputSource provides the most recent lines of application output
+type OutputSource interface {
+	Tail(n int) []string
 }
 
 // Event represents a rebuild event
@@ -25,6 +41,11 @@ type Event struct {
 	AffectedCount int
 	Duration      time.Duration
 	EventType     EventType
+	PID           int
+	Err           error
+	Message       string
+	Failing       []string
+	Stages        []events.StageResult
 }
 
 // EventType defines the type of event
@@ -33,6 +54,13 @@ type EventType int
 const (
 	RebuildEvent EventType = iota
 	CacheHitEvent
+	ReadyEvent
+	StartFailedEvent
+	ExitEvent
+	CrashLoopEvent
+	TestEvent
+	PipelineEvent
+	NoOpBuildEvent
 )
 
 // NewDashboard creates a new dashboard instance
@@ -43,6 +71,44 @@ func NewDashboard() *Dashboard {
 	}
 }
 
+// SetOutput sets where the summary reads recent application output from
+func (d *Dashboard) SetOutput(src OutputSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.output = src
+}
+
+// HandleEvent updates the dashboard from optimizer events
+func (d *Dashboard) HandleEvent(e events.Event) {
+	switch ev := e.(type) {
+	case events.BuildSucceeded:
+		// The initial build has no trigger and is not a rebuild
+		if ev.Trigger != "" {
+			d.UpdateRebuild(ev.Trigger, ev.AffectedCount, ev.Duration)
+		}
+	case events.CacheHit:
+		d.UpdateCacheHit(ev.Path)
+	case events.BuildUnchanged:
+		d.UpdateNoOpBuild(ev.Trigger, ev.PID)
+	case events.ProcessReady:
+		d.UpdateProcessReady(ev.PID, ev.Duration)
+	case events.ProcessStartFailed:
+		d.UpdateStartFailed(ev.PID, ev.Err)
+	case events.ProcessExited:
+		if ev.Unexpected {
+			d.UpdateUnexpectedExit(ev.PID, ev.ExitCode, ev.Signal)
+		}
+	case events.CrashLoop:
+		d.UpdateCrashLoop(ev.PID, ev.Failures, ev.Window)
+	case events.TestRunFinished:
+		if ev.Err == nil {
+			d.UpdateTestRun(ev)
+		}
+	case events.PipelineFinished:
+		d.UpdatePipeline(ev)
+	}
+}
+
 // UpdateRebuild records a rebuild event
 func (d *Dashboard) UpdateRebuild(filePath string, affectedCount int, duration time.Duration) {
 	d.mu.Lock()
@@ -92,6 +158,179 @@ func (d *Dashboard) UpdateCacheHit(filePath string) {
 	d.displayEvent(event)
 }
 
+// UpdateNoOpBuild records a build whose binary matched the running one
+func (d *Dashboard) UpdateNoOpBuild(filePath string, pid int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event := Event{
+		Timestamp: time.Now(),
+		FilePath:  filePath,
+		PID:       pid,
+		EventType: NoOpBuildEvent,
+	}
+
+	d.totalNoOpBuilds++
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdateProcessReady records a process that passed its readiness probes
+func (d *Dashboard) UpdateProcessReady(pid int, duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event := Event{
+		Timestamp: time.Now(),
+		PID:       pid,
+		Duration:  duration,
+		EventType: ReadyEvent,
+	}
+
+	d.totalReady++
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdateStartFailed records a process that failed its readiness probes
+func (d *Dashboard) UpdateStartFailed(pid int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event := Event{
+		Timestamp: time.Now(),
+		PID:       pid,
+		Err:       err,
+		EventType: StartFailedEvent,
+	}
+
+	d.totalFailedStarts++
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdateUnexpectedExit records a process that exited on its own
+func (d *Dashboard) UpdateUnexpectedExit(pid int, exitCode int, signal string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := fmt.Sprintf("exit code %d", exitCode)
+	if signal != "" {
+		status = "signal: " + signal
+	}
+
+	event := Event{
+		Timestamp: time.Now(),
+		PID:       pid,
+		Message:   status,
+		EventType: ExitEvent,
+	}
+
+	d.totalCrashes++
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdateCrashLoop records that restarts stopped because of a crash loop
+func (d *Dashboard) UpdateCrashLoop(pid int, failures int, window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event := Event{
+		Timestamp: time.Now(),
+		PID:       pid,
+		Message:   fmt.Sprintf("%d exits within %v", failures, window),
+		EventType: CrashLoopEvent,
+	}
+
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdateTestRun records the results of a test run
+func (d *Dashboard) UpdateTestRun(run events.TestRunFinished) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var failing []string
+	for _, f := range run.Failures {
+		if f.Test == "" {
+			failing = append(failing, f.Package+" (package)")
+		} else {
+			failing = append(failing, f.Package+"."+f.Test)
+		}
+	}
+
+	event := Event{
+		Timestamp:     time.Now(),
+		FilePath:      run.Trigger,
+		AffectedCount: len(run.Packages),
+		Duration:      run.Duration,
+		Message:       fmt.Sprintf("%d passed, %d failed, %d skipped", run.Passed, run.Failed, run.Skipped),
+		Failing:       failing,
+		EventType:     TestEvent,
+	}
+
+	d.totalTestRuns++
+	d.totalTestsFailed += run.Failed
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// UpdatePipeline records a pipeline run with the timing and result of each stage
+func (d *Dashboard) UpdatePipeline(run events.PipelineFinished) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	filePath := run.Trigger
+	if filePath == "" {
+		filePath = "initial build"
+	}
+	status := "passed"
+	if run.Err != nil {
+		status = "failed"
+		d.totalPipelineErrs++
+	}
+
+	event := Event{
+		Timestamp: time.Now(),
+		FilePath:  filePath,
+		Duration:  run.Duration,
+		Err:       run.Err,
+		Message:   status,
+		Stages:    run.Stages,
+		EventType: PipelineEvent,
+	}
+
+	d.totalPipelines++
+	d.appendEvent(event)
+	d.displayEvent(event)
+}
+
+// stageLine formats a pipeline stage for the event list
+func stageLine(s events.StageResult) string {
+	line := fmt.Sprintf("%-12s %-8s", s.Name, s.Status)
+	if s.Status == events.StageSkipped {
+		return line + " (" + s.Reason + ")"
+	}
+
+	line += fmt.Sprintf(" %v", s.Duration)
+	if s.Advisory && s.Status == events.StageFailed {
+		line += " (advisory)"
+	}
+	return line
+}
+
+// appendEvent stores an event, keeping only the last N
+func (d *Dashboard) appendEvent(event Event) {
+	d.events = append(d.events, event)
+	d.lastUpdate = time.Now()
+
+	if len(d.events) > d.maxEvents {
+		d.events = d.events[len(d.events)-d.maxEvents:]
+	}
+}
+
 // displayEvent prints an event to the console
 func (d *Dashboard) displayEvent(event Event) {
 	timestamp := event.Timestamp.Format("15:04:05")
@@ -103,6 +342,33 @@ func (d *Dashboard) displayEvent(event Event) {
 	case CacheHitEvent:
 		fmt.Printf("[%s] CACHE HIT: %s (skipped rebuild)\n",
 			timestamp, event.FilePath)
+	case NoOpBuildEvent:
+		fmt.Printf("[%s] NO-OP BUILD: %s (binary unchanged, kept PID %d)\n",
+			timestamp, event.FilePath, event.PID)
+	case ReadyEvent:
+		fmt.Printf("[%s] READY: PID %d (took: %v)\n",
+			timestamp, event.PID, event.Duration)
+	case StartFailedEvent:
+		fmt.Printf("[%s] START FAILED: PID %d (%v)\n",
+			timestamp, event.PID, event.Err)
+	case ExitEvent:
+		fmt.Printf("[%s] EXITED: PID %d (%s)\n",
+			timestamp, event.PID, event.Message)
+	case CrashLoopEvent:
+		fmt.Printf("[%s] CRASH LOOP: PID %d (%s)\n",
+			timestamp, event.PID, event.Message)
+	case TestEvent:
+		fmt.Printf("[%s] TESTS: %s (%d packages, %s, took: %v)\n",
+			timestamp, event.FilePath, event.AffectedCount, event.Message, event.Duration)
+		for _, name := range event.Failing {
+			fmt.Printf("    FAIL %s\n", name)
+		}
+	case PipelineEvent:
+		fmt.Printf("[%s] PIPELINE: %s (%s, took: %v)\n",
+			timestamp, event.FilePath, event.Message, event.Duration)
+		for _, s := range event.Stages {
+			fmt.Printf("    %s\n", stageLine(s))
+		}
 	}
 }
 
@@ -116,7 +382,7 @@ func (d *Dashboard) PrintSummary() {
 	fmt.Println("HOT RELOAD OPTIMIZER - DASHBOARD")
 	fmt.Println(separator)
 
-	if d.totalRebuilds == 0 && d.totalCacheHits == 0 {
+	if len(d.events) == 0 {
 		fmt.Println("No events yet. Waiting for file changes...")
 		return
 	}
@@ -137,6 +403,25 @@ func (d *Dashboard) PrintSummary() {
 		fmt.Printf("  Cache Hit Rate:  %.2f%%\n", cacheHitRate)
 	}
 
+	if d.totalNoOpBuilds > 0 {
+		fmt.Printf("  No-op Builds:    %d\n", d.totalNoOpBuilds)
+	}
+	if d.totalReady > 0 || d.totalFailedStarts > 0 {
+		fmt.Printf("  Ready Starts:    %d\n", d.totalReady)
+		fmt.Printf("  Failed Starts:   %d\n", d.totalFailedStarts)
+	}
+	if d.totalCrashes > 0 {
+		fmt.Printf("  Process Crashes: %d\n", d.totalCrashes)
+	}
+	if d.totalTestRuns > 0 {
+		fmt.Printf("  Test Runs:       %d\n", d.totalTestRuns)
+		fmt.Printf("  Tests Failed:    %d\n", d.totalTestsFailed)
+	}
+	if d.totalPipelines > 0 {
+		fmt.Printf("  Pipeline Runs:   %d\n", d.totalPipelines)
+		fmt.Printf("  Pipeline Fails:  %d\n", d.totalPipelineErrs)
+	}
+
 	fmt.Printf("\nRecent Events (last %d):\n", min(len(d.events), 10))
 	recentEvents := d.events
 	if len(recentEvents) > 10 {
@@ -154,6 +439,42 @@ func (d *Dashboard) PrintSummary() {
 		case CacheHitEvent:
 			fmt.Printf("  [%s] CACHE HIT: %s (cached)\n",
 				timestamp, event.FilePath)
+		case NoOpBuildEvent:
+			fmt.Printf("  [%s] NO-OP BUILD: %s (kept PID %d)\n",
+				timestamp, event.FilePath, event.PID)
+		case ReadyEvent:
+			fmt.Printf("  [%s] READY: PID %d (%v)\n",
+				timestamp, event.PID, event.Duration)
+		case StartFailedEvent:
+			fmt.Printf("  [%s] START FAILED: PID %d (%v)\n",
+				timestamp, event.PID, event.Err)
+		case ExitEvent:
+			fmt.Printf("  [%s] EXITED: PID %d (%s)\n",
+				timestamp, event.PID, event.Message)
+		case CrashLoopEvent:
+			fmt.Printf("  [%s] CRASH LOOP: PID %d (%s)\n",
+				timestamp, event.PID, event.Message)
+		case TestEvent:
+			fmt.Printf("  [%s] TESTS: %s (%s)\n",
+				timestamp, event.FilePath, event.Message)
+			for _, name := range event.Failing {
+				fmt.Printf("      FAIL %s\n", name)
+			}
+		case PipelineEvent:
+			fmt.Printf("  [%s] PIPELINE: %s (%s, %v)\n",
+				timestamp, event.FilePath, event.Message, event.Duration)
+			for _, s := range event.Stages {
+				fmt.Printf("      %s\n", stageLine(s))
+			}
+		}
+	}
+
+	if d.output != nil {
+		if lines := d.output.Tail(10); len(lines) > 0 {
+			fmt.Printf("\nRecent Output (last %d lines):\n", len(lines))
+			for _, line := range lines {
+				fmt.Printf("  %s\n", line)
+			}
 		}
 	}
 
@@ -169,6 +490,14 @@ func (d *Dashboard) GetMetrics() map[string]interface{} {
 		"total_rebuilds":   d.totalRebuilds,
 		"total_cache_hits": d.totalCacheHits,
 		"total_affected":   d.totalAffected,
+		"noop_builds":      d.totalNoOpBuilds,
+		"total_ready":      d.totalReady,
+		"failed_starts":    d.totalFailedStarts,
+		"process_crashes":  d.totalCrashes,
+		"test_runs":        d.totalTestRuns,
+		"tests_failed":     d.totalTestsFailed,
+		"pipeline_runs":    d.totalPipelines,
+		"pipelines_failed": d.totalPipelineErrs,
 		"last_update":      d.lastUpdate,
 		"event_count":      len(d.events),
 	}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize is the queue length used when a subscriber does not set one
+const DefaultQueueSize = 256
+
+// Subscriber consumes events published on the bus
+type Subscriber interface {
+	HandleEvent(Event)
+}
+
+// Essential is implemented by subscribers whose state follows some events,
+// such as the start and end of builds. Those events are never dropped:
+// Publish waits for room in the subscriber's queue instead. Such a
+// subscriber must not publish from HandleEvent.
+type Essential interface {
+	Essential(Event) bool
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface
+type SubscriberFunc func(Event)
+
+// HandleEvent calls f(e)
+func (f SubscriberFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+// subscription delivers events to one subscriber from its own queue
+type subscription struct {
+	name    string
+	sub     Subscriber
+	queue   chan Event
+	dropped atomic.Int64
+	done    chan struct{}
+}
+
+// Bus fans events out to subscribers. Each subscriber has a bounded queue
+// drained by its own goroutine, so a slow consumer never blocks the
+// publisher; events that do not fit in a full queue are dropped, unless
+// the subscriber marks them Essential.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscription
+	closed bool
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a subscriber with a queue of the given size
+func (b *Bus) Subscribe(name string, sub Subscriber, queueSize int) {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	s := &subscription{
+		name:  name,
+		sub:   sub,
+		queue: make(chan Event, queueSize),
+		done:  make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.subs = append(b.subs, s)
+
+	go s.run()
+}
+
+// Publish delivers an event to every subscriber without blocking, except
+// on a full queue of a subscriber the event is essential to
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for _, s := range b.subs {
+		select {
+		case s.queue <- e:
+		default:
+			if essential, ok := s.sub.(Essential); ok && essential.Essential(e) {
+				s.queue <- e
+				continue
+			}
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns the number of events dropped per subscriber
+func (b *Bus) Dropped() map[string]int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	dropped := make(map[string]int64, len(b.subs))
+	for _, s := range b.subs {
+		dropped[s.name] = s.dropped.Load()
+	}
+	return dropped
+}
+
+// Close stops accepting events and waits for subscribers to drain their queues
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	for _, s := range subs {
+		close(s.queue)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		<-s.done
+		if n := s.dropped.Load(); n > 0 {
+			fmt.Printf("⚠️  Event subscriber %q dropped %d events\n", s.name, n)
+		}
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (s *subscription) run() {
+	defer close(s.done)
+	for e := range s.queue {
+		s.deliver(e)
+	}
+}
+
+// deliver hands one event to the subscriber, isolating its panics
+func (s *subscription) deliver(e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("⚠️  Event subscriber %q panicked: %v\n", s.name, r)
+		}
+	}()
+	s.sub.HandleEvent(e)
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+// blockingSubscriber records events, blocking in HandleEvent until released
+type blockingSubscriber struct {
+	release chan struct{}
+	mu      sync.Mutex
+	got     []Event
+}
+
+func (s *blockingSubscriber) HandleEvent(e Event) {
+	<-s.release
+	s.mu.Lock()
+	s.got = append(s.got, e)
+	s.mu.Unlock()
+}
+
+// essentialSubscriber never misses build events
+type essentialSubscriber struct {
+	*blockingSubscriber
+}
+
+func (s essentialSubscriber) Essential(e Event) bool {
+	_, ok := e.(BuildStarted)
+	return ok
+}
+
+func TestBusFullQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		essential   bool
+		wantBuilds  int
+		wantDropped bool
+	}{
+		{name: "events dropped", wantBuilds: 1, wantDropped: true},
+		{name: "essential events kept", essential: true, wantBuilds: 3, wantDropped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocking := &blockingSubscriber{release: make(chan struct{})}
+			var sub Subscriber = blocking
+			if tt.essential {
+				sub = essentialSubscriber{blocking}
+			}
+
+			b := NewBus()
+			b.Subscribe("test", sub, 1)
+
+			// The first event is being handled, the second fills the queue
+			published := make(chan struct{})
+			go func() {
+				defer close(published)
+				b.Publish(BuildStarted{Trigger: "1"})
+				for i := 0; i < 3; i++ {
+					b.Publish(FileChanged{})
+				}
+				b.Publish(BuildStarted{Trigger: "2"})
+				b.Publish(BuildStarted{Trigger: "3"})
+			}()
+
+			if !tt.essential {
+				<-published
+			}
+			close(blocking.release)
+			<-published
+			b.Close()
+
+			builds := 0
+			for _, e := range blocking.got {
+				if _, ok := e.(BuildStarted); ok {
+					builds++
+				}
+			}
+			if builds != tt.wantBuilds {
+				t.Errorf("subscriber got %d builds, want %d", builds, tt.wantBuilds)
+			}
+			if dropped := b.Dropped()["test"] > 0; dropped != tt.wantDropped {
+				t.Errorf("dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package events
+
+import (
+	"time"
+)
+
+// Event is implemented by every event published on the bus
+type Event interface {
+	Timestamp() time.Time
+}
+
+// Meta holds the fields shared by all events
+type Meta struct {
+	At time.Time
+}
+
+// Timestamp returns when the event happened
+func (m Meta) Timestamp() time.Time {
+	return m.At
+}
+
+// Now returns event metadata stamped with the current time
+func Now() Meta {
+	return Meta{At: time.Now()}
+}
+
+// FileChanged is published when the watcher reports a modified file
+type FileChanged struct {
+	Meta
+	Path string
+}
+
+// CacheHit is published when a changed file is still valid in the cache
+type CacheHit struct {
+	Meta
+	Path string
+}
+
+// BuildStarted is published before the affected files are rebuilt.
+// Trigger is empty for the initial build.
+type BuildStarted struct {
+	Meta
+	Trigger       string
+	Plugin        string
+	AffectedFiles []string
+}
+
+// BuildSucceeded is published once a rebuild has completed. Plugin is
+// empty in analysis-only mode, where no build tool runs.
+type BuildSucceeded struct {
+	Meta
+	Trigger       string
+	Plugin        string
+	AffectedCount int
+	Duration      time.Duration
+	BuildDuration time.Duration
+}
+
+// BuildFailed is published when the build plugin returns an error
+type BuildFailed struct {
+	Meta
+	Trigger       string
+	Plugin        string
+	AffectedCount int
+	Duration      time.Duration
+	Err           error
+}
+
+// ProcessStarted is published after the application process is started.
+// Name identifies the process: "app" for the built application or the
+// name of a process from the Procfile or config.
+type ProcessStarted struct {
+	Meta
+	Name string
+	PID  int
+}
+
+// ProcessReady is published once the started process passes its readiness probes
+type ProcessReady struct {
+	Meta
+	Name     string
+	PID      int
+	Duration time.Duration
+}
+
+// ProcessStartFailed is published when a started process fails its readiness probes
+type ProcessStartFailed struct {
+	Meta
+	Name string
+	PID  int
+	Err  error
+}
+
+// ProcessStopping is published before the reloader stops a process
+type ProcessStopping struct {
+	Meta
+	Name string
+	PID  int
+}
+
+// RestartStarted is published when the reloader starts a service, or
+// restarts it to take up a change. Initial is set for the first start.
+type RestartStarted struct {
+	Meta
+	Name    string
+	Initial bool
+}
+
+// RestartFinished is published when a start or restart has finished. Err
+// is set when the service could not be started.
+type RestartFinished struct {
+	Meta
+	Name    string
+	Initial bool
+	Err     error
+}
+
+// ProcessExited is published after the application process has stopped.
+// Unexpected is set when the process exited on its own rather than being
+// stopped by the reloader. Signal is empty unless a signal ended the process.
+// Output holds the last lines the process printed before an unexpected exit.
+type ProcessExited struct {
+	Meta
+	Name       string
+	PID        int
+	ExitCode   int
+	Signal     string
+	Unexpected bool
+	Err        error
+	Output     []string
+}
+
+// TestRunStarted is published before the tests of affected packages run
+type TestRunStarted struct {
+	Meta
+	Trigger  string
+	Packages []string
+}
+
+// TestRunFinished is published after a test run. Passed, Failed and Skipped
+// count top-level tests. Err is set when the tests could not be run.
+type TestRunFinished struct {
+	Meta
+	Trigger  string
+	Packages []string
+	Passed   int
+	Failed   int
+	Skipped  int
+	Failures []TestFailure
+	Duration time.Duration
+	Err      error
+}
+
+// TestFailure is a failing test, or a package that failed to build when
+// Test is empty, with its output
+type TestFailure struct {
+	Package string
+	Test    string
+	Output  []string
+}
+
+// RebuildExplained is published before a rebuild with the reason for it
+type RebuildExplained struct {
+	Meta
+	Explanation Explanation
+}
+
+// Explanation records why a changed file caused a rebuild: why its cache
+// entry missed and, for every affected file, the chain of dependents
+// leading from the changed file to it. Paths are relative to the project.
+type Explanation struct {
+	Trigger  string         `json:"trigger"`
+	Time     time.Time      `json:"time"`
+	Reason   string         `json:"reason"`
+	Affected []AffectedFile `json:"affected"`
+}
+
+// AffectedFile is a file made dirty by a change and the dependency chain
+// through which it was reached. Kinds[i] is how Chain[i+1] references
+// Chain[i], such as "import" or "dynamic".
+type AffectedFile struct {
+	File  string   `json:"file"`
+	Chain []string `json:"chain"`
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// BuildUnchanged is published when a build produced the same binary as
+// the one the process is running, so the process is not restarted
+type BuildUnchanged struct {
+	Meta
+	Trigger string
+	Name    string
+	PID     int
+}
+
+// ReloadRequested is published when a change only needs the browser to reload
+type ReloadRequested struct {
+	Meta
+	Trigger string
+}
+
+// StageFinished is published when a pipeline stage passes, fails or is skipped
+type StageFinished struct {
+	Meta
+	Trigger string
+	Stage   StageResult
+}
+
+// PipelineFinished is published after every stage of a pipeline has
+// finished or been skipped. Err is set when a required stage failed.
+type PipelineFinished struct {
+	Meta
+	Trigger  string
+	Stages   []StageResult
+	Duration time.Duration
+	Err      error
+}
+
+// Stage statuses
+const (
+	StagePassed  = "passed"
+	StageFailed  = "failed"
+	StageSkipped = "skipped"
+)
+
+// StageResult is the outcome of a pipeline stage. Reason explains a
+// skipped stage; Output holds the last lines printed by a failed command.
+type StageResult struct {
+	Name     string
+	Status   string
+	Advisory bool
+	Duration time.Duration
+	Reason   string
+	Err      error
+	Output   []string
+}
+
+// CrashLoop is published when the process keeps exiting and is no longer restarted
+type CrashLoop struct {
+	Meta
+	Name     string
+	PID      int
+	Failures int
+	Window   time.Duration
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConsoleLogger prints build and process events to stdout
+type ConsoleLogger struct{}
+
+// NewConsoleLogger creates a console logger subscriber
+func NewConsoleLogger() *ConsoleLogger {
+	return &ConsoleLogger{}
+}
+
+// HandleEvent prints a human readable line for an event
+func (l *ConsoleLogger) HandleEvent(e Event) {
+	switch ev := e.(type) {
+	case BuildStarted:
+		if ev.Trigger == "" {
+			fmt.Println("\n🔨 Performing initial build...")
+		} else {
+			fmt.Printf("\n🔨 Building (affected files: %d)...\n", len(ev.AffectedFiles))
+		}
+	case BuildSucceeded:
+		if ev.Plugin != "" {
+			fmt.Printf("✅ Build successful (took %v)\n", ev.BuildDuration)
+		}
+	case BuildUnchanged:
+		fmt.Printf("🟰 Binary unchanged, keeping %s\n", processLabel(ev.Name, ev.PID))
+	case BuildFailed:
+		fmt.Printf("❌ Build failed: %v\n", ev.Err)
+	case StageFinished:
+		printStage(ev.Stage)
+	case PipelineFinished:
+		if ev.Err != nil {
+			fmt.Printf("⛔ Pipeline stopped: %v\n", ev.Err)
+		} else {
+			fmt.Printf("🏁 Pipeline finished (took %v)\n", ev.Duration)
+		}
+	case TestRunStarted:
+		fmt.Printf("\n🧪 Testing %d affected package(s)...\n", len(ev.Packages))
+	case TestRunFinished:
+		printTestRun(ev)
+	case RestartStarted:
+		if ev.Initial {
+			fmt.Printf("▶️  Starting %s...\n", serviceLabel(ev.Name))
+		} else {
+			fmt.Printf("🔄 Restarting %s...\n", serviceLabel(ev.Name))
+		}
+	case RestartFinished:
+		switch {
+		case ev.Err != nil && ev.Initial:
+			fmt.Printf("⚠️  Failed to start %s: %v\n", serviceLabel(ev.Name), ev.Err)
+		case ev.Err != nil:
+			fmt.Printf("⚠️  Failed to restart %s: %v\n", serviceLabel(ev.Name), ev.Err)
+		case ev.Initial && isApp(ev.Name):
+			fmt.Print("✅ Application started successfully\n\n")
+		case isApp(ev.Name):
+			fmt.Println("✅ Application restarted successfully")
+		}
+	case ProcessStarted:
+		if isApp(ev.Name) {
+			fmt.Printf("✅ Started new process with PID: %d\n", ev.PID)
+		} else {
+			fmt.Printf("✅ Started %s with PID: %d\n", ev.Name, ev.PID)
+		}
+	case ProcessReady:
+		fmt.Printf("🟢 %s ready (took %v)\n", processLabel(ev.Name, ev.PID), ev.Duration)
+	case ProcessStartFailed:
+		fmt.Printf("🔴 %s failed to start: %v\n", processLabel(ev.Name, ev.PID), ev.Err)
+	case ProcessExited:
+		status := fmt.Sprintf("exit code %d", ev.ExitCode)
+		if ev.Signal != "" {
+			status = "signal: " + ev.Signal
+		}
+		if ev.Unexpected {
+			fmt.Printf("💀 %s exited unexpectedly (%s)\n", processLabel(ev.Name, ev.PID), status)
+			if len(ev.Output) > 0 {
+				fmt.Println("   Last output:")
+				for _, line := range ev.Output {
+					fmt.Printf("   │ %s\n", line)
+				}
+			}
+		} else {
+			fmt.Printf("%s exited (%s)\n", processLabel(ev.Name, ev.PID), status)
+		}
+	}
+}
+
+// ExplainLogger prints why each rebuild happened
+type ExplainLogger struct{}
+
+// NewExplainLogger creates a subscriber printing rebuild explanations
+func NewExplainLogger() *ExplainLogger {
+	return &ExplainLogger{}
+}
+
+// HandleEvent prints rebuild explanations and ignores other events
+func (l *ExplainLogger) HandleEvent(e Event) {
+	if ev, ok := e.(RebuildExplained); ok {
+		PrintExplanation(ev.Explanation)
+	}
+}
+
+// PrintExplanation prints the cache miss reason of a rebuild and the
+// dependency chain that made each affected file dirty
+func PrintExplanation(e Explanation) {
+	fmt.Printf("🔎 %s changed (%s), %d affected file(s)\n", e.Trigger, e.Reason, len(e.Affected))
+	for _, a := range e.Affected {
+		if a.File == e.Trigger {
+			fmt.Printf("   %s (changed)\n", a.File)
+		} else if len(a.Chain) <= 1 {
+			fmt.Printf("   %s (cache key changed)\n", a.File)
+		} else {
+			fmt.Printf("   %s via %s\n", a.File, formatChain(a))
+		}
+	}
+}
+
+// formatChain joins a dependency chain, labelling the links that are not
+// plain imports with their kind
+func formatChain(a AffectedFile) string {
+	var b strings.Builder
+	for i, file := range a.Chain {
+		if i > 0 {
+			b.WriteString(" → ")
+		}
+		b.WriteString(file)
+		if i > 0 && i-1 < len(a.Kinds) && a.Kinds[i-1] != "" && a.Kinds[i-1] != "import" {
+			fmt.Fprintf(&b, " [%s]", a.Kinds[i-1])
+		}
+	}
+	return b.String()
+}
+
+// isApp reports whether name is the built application rather than a named process
+func isApp(name string) bool {
+	return name == "" || name == "app"
+}
+
+// serviceLabel names the application or a named process in log lines
+func serviceLabel(name string) string {
+	if isApp(name) {
+		return "application"
+	}
+	return name
+}
+
+// processLabel names a process in log lines
+func processLabel(name string, pid int) string {
+	if isApp(name) {
+		return fmt.Sprintf("Process %d", pid)
+	}
+	return fmt.Sprintf("%s (PID %d)", name, pid)
+}
+
+// printStage prints the outcome of a pipeline stage and the output of a
+// failed command
+func printStage(s StageResult) {
+	switch s.Status {
+	case StagePassed:
+		fmt.Printf("✅ Stage %s passed (took %v)\n", s.Name, s.Duration)
+		return
+	case StageSkipped:
+		fmt.Printf("⏭️  Stage %s skipped (%s)\n", s.Name, s.Reason)
+		return
+	}
+
+	if s.Advisory {
+		fmt.Printf("⚠️  Stage %s failed (advisory): %v\n", s.Name, s.Err)
+	} else {
+		fmt.Printf("❌ Stage %s failed: %v\n", s.Name, s.Err)
+	}
+	for _, line := range s.Output {
+		fmt.Printf("   │ %s\n", line)
+	}
+}
+
+// printTestRun prints the counts of a test run and the output of each failure
+func printTestRun(ev TestRunFinished) {
+	if ev.Err != nil {
+		fmt.Printf("❌ Tests could not run: %v\n", ev.Err)
+		return
+	}
+
+	counts := fmt.Sprintf("%d passed, %d failed, %d skipped", ev.Passed, ev.Failed, ev.Skipped)
+	if len(ev.Failures) == 0 {
+		fmt.Printf("✅ Tests passed: %s (took %v)\n", counts, ev.Duration)
+		return
+	}
+
+	fmt.Printf("❌ Tests failed: %s (took %v)\n", counts, ev.Duration)
+	for _, f := range ev.Failures {
+		if f.Test == "" {
+			fmt.Printf("   --- %s (package failed)\n", f.Package)
+		} else {
+			fmt.Printf("   --- %s %s\n", f.Package, f.Test)
+		}
+		for _, line := range f.Output {
+			fmt.Printf("   │ %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/process"
+)
+
+// DefaultTimeout is used for hooks that do not set their own timeout
+const DefaultTimeout = 30 * time.Second
+
+// Stage identifies a point in the build and restart lifecycle
+type Stage string
+
+const (
+	PreBuild       Stage = "pre-build"
+	PostBuild      Stage = "post-build"
+	OnBuildFailure Stage = "on-build-failure"
+	PreRestart     Stage = "pre-restart"
+	PostRestart    Stage = "post-restart"
+	OnShutdown     Stage = "on-shutdown"
+)
+
+// Context carries information about the current change to hook commands
+type Context struct {
+	ChangedFiles  []string
+	AffectedCount int
+	Duration      time.Duration
+	Err           error
+	PID           int
+	Process       string
+}
+
+// Hook is a single command bound to a stage
+type Hook struct {
+	Command      string
+	Timeout      time.Duration
+	AllowFailure bool
+}
+
+// Runner executes the hooks configured for each stage
+type Runner struct {
+	dir   string
+	hooks map[Stage][]Hook
+}
+
+// NewRunner creates a hook runner that executes commands in dir
+func NewRunner(dir string, cfg config.HooksConfig) *Runner {
+	r := &Runner{
+		dir:   dir,
+		hooks: make(map[Stage][]Hook),
+	}
+
+	r.add(PreBuild, cfg.PreBuild)
+	r.add(PostBuild, cfg.PostBuild)
+	r.add(OnBuildFailure, cfg.OnBuildFailure)
+	r.add(PreRestart, cfg.PreRestart)
+	r.add(PostRestart, cfg.PostRestart)
+	r.add(OnShutdown, cfg.OnShutdown)
+
+	return r
+}
+
+// add converts hook configs for a stage
+func (r *Runner) add(stage Stage, cfgs []config.HookConfig) {
+	for _, c := range cfgs {
+		if strings.TrimSpace(c.Command) == "" {
+			continue
+		}
+		timeout := c.Timeout.Duration
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		r.hooks[stage] = append(r.hooks[stage], Hook{
+			Command:      c.Command,
+			Timeout:      timeout,
+			AllowFailure: c.AllowFailure,
+		})
+	}
+}
+
+// Has reports whether any hook is configured for the stage
+func (r *Runner) Has(stage Stage) bool {
+	return len(r.hooks[stage]) > 0
+}
+
+// Run executes the hooks of a stage in order. A failing hook vetoes the
+// step guarded by the stage: Run stops and returns its error, unless the
+// hook allows failure.
+func (r *Runner) Run(stage Stage, hctx Context) error {
+	for _, hook := range r.hooks[stage] {
+		fmt.Printf("🪝 Running %s hook: %s\n", stage, hook.Command)
+
+		if err := r.runHook(stage, hook, hctx); err != nil {
+			if hook.AllowFailure {
+				fmt.Printf("⚠️  %s hook failed (ignored): %v\n", stage, err)
+				continue
+			}
+			return fmt.Errorf("%s hook %q vetoed: %w", stage, hook.Command, err)
+		}
+	}
+	return nil
+}
+
+// waitDelay bounds the wait for a killed hook's output to be closed
+const waitDelay = time.Second
+
+// runHook executes a single hook command with its timeout. On timeout the
+// shell and everything it started are killed.
+func (r *Runner) runHook(stage Stage, hook Hook, hctx Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	process.KillGroupOnCancel(cmd)
+	cmd.WaitDelay = waitDelay
+	cmd.Dir = r.dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), environ(stage, hctx)...)
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v", hook.Timeout)
+	}
+	return err
+}
+
+// environ builds the HOTRELOADER_* variables passed to hook commands
+func environ(stage Stage, hctx Context) []string {
+	env := []string{
+		"HOTRELOADER_STAGE=" + string(stage),
+		"HOTRELOADER_CHANGED_FILES=" + strings.Join(hctx.ChangedFiles, string(os.PathListSeparator)),
+		"HOTRELOADER_AFFECTED_COUNT=" + strconv.Itoa(hctx.AffectedCount),
+		"HOTRELOADER_DURATION=" + hctx.Duration.String(),
+		"HOTRELOADER_DURATION_MS=" + strconv.FormatInt(hctx.Duration.Milliseconds(), 10),
+	}
+
+	if hctx.Err != nil {
+		env = append(env, "HOTRELOADER_ERROR="+hctx.Err.Error())
+	}
+	if hctx.PID > 0 {
+		env = append(env, "HOTRELOADER_PID="+strconv.Itoa(hctx.PID))
+	}
+	if hctx.Process != "" {
+		env = append(env, "HOTRELOADER_PROCESS="+hctx.Process)
+	}
+
+	return env
+}
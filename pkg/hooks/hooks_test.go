@@ -0,0 +1,94 @@
+package hooks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   []config.HookConfig
+		wantErr string
+		wantRan int
+	}{
+		{
+			name:    "all hooks run",
+			hooks:   []config.HookConfig{{Command: "echo 1 >> ran"}, {Command: "echo 2 >> ran"}},
+			wantRan: 2,
+		},
+		{
+			name:    "failure vetoes",
+			hooks:   []config.HookConfig{{Command: "exit 3"}, {Command: "echo 2 >> ran"}},
+			wantErr: `pre-build hook "exit 3" vetoed: exit status 3`,
+		},
+		{
+			name:    "allowed failure continues",
+			hooks:   []config.HookConfig{{Command: "exit 3", AllowFailure: true}, {Command: "echo 2 >> ran"}},
+			wantRan: 1,
+		},
+		{
+			name:    "timeout vetoes",
+			hooks:   []config.HookConfig{{Command: "sleep 5", Timeout: config.Duration{Duration: 100 * time.Millisecond}}},
+			wantErr: "timed out after 100ms",
+		},
+		{
+			name:  "blank command skipped",
+			hooks: []config.HookConfig{{Command: "  "}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			r := NewRunner(dir, config.HooksConfig{PreBuild: tt.hooks})
+
+			err := r.Run(PreBuild, Context{})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Run error = %v, want %q", err, tt.wantErr)
+			}
+
+			data, _ := os.ReadFile(filepath.Join(dir, "ran"))
+			if got := strings.Count(string(data), "\n"); got != tt.wantRan {
+				t.Errorf("%d hooks ran, want %d", got, tt.wantRan)
+			}
+		})
+	}
+}
+
+func TestRunPassesContext(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRunner(dir, config.HooksConfig{PostRestart: []config.HookConfig{{
+		Command: `echo "$HOTRELOADER_STAGE|$HOTRELOADER_CHANGED_FILES|$HOTRELOADER_AFFECTED_COUNT|$HOTRELOADER_DURATION_MS|$HOTRELOADER_ERROR|$HOTRELOADER_PID|$HOTRELOADER_PROCESS" > env`,
+	}}})
+
+	hctx := Context{
+		ChangedFiles:  []string{"a.go", "b.go"},
+		AffectedCount: 3,
+		Duration:      1500 * time.Millisecond,
+		Err:           errors.New("boom"),
+		PID:           42,
+		Process:       "web",
+	}
+	if err := r.Run(PostRestart, hctx); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "post-restart|a.go" + string(os.PathListSeparator) + "b.go|3|1500|boom|42|web"
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("hook environment = %q, want %q", got, want)
+	}
+}
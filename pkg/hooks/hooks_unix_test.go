@@ -0,0 +1,37 @@
+//go:build unix
+
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/internal/proctest"
+)
+
+func TestTimeoutKillsProcessGroup(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRunner(dir, config.HooksConfig{PreBuild: []config.HookConfig{
+		{
+			Command:      "sleep 30 & echo $! > pid; wait",
+			Timeout:      config.Duration{Duration: 200 * time.Millisecond},
+			AllowFailure: true,
+		},
+		{Command: "echo next > ran"},
+	}})
+
+	if err := r.Run(PreBuild, Context{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The timed out hook's background job must not survive into the build
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err != nil {
+		t.Errorf("hook after the timed out one did not run: %v", err)
+	}
+	if pid := proctest.ReadPID(t, filepath.Join(dir, "pid")); !proctest.WaitGone(pid, 2*time.Second) {
+		t.Errorf("background job %d of the timed out hook survived", pid)
+	}
+}
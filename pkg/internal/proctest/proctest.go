@@ -0,0 +1,58 @@
+//go:build unix
+
+// Package proctest checks on processes started by tests
+package proctest
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Alive reports whether a process exists and is not a zombie. A killed
+// process reparented to init may remain a zombie until init reaps it.
+func Alive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// WaitGone waits up to timeout for a process to exit and reports whether
+// it did. A process still alive afterwards is killed so it does not
+// outlive the test.
+func WaitGone(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for Alive(pid) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if Alive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		return false
+	}
+	return true
+}
+
+// ReadPID reads the PID a test command wrote to a file
+func ReadPID(t testing.TB, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("PID in %s: %v", path, err)
+	}
+	return pid
+}
@@ -0,0 +1,71 @@
+// Live reload client injected by the hot reloader
+(function () {
+  if (window.__hotreloader) return;
+  window.__hotreloader = true;
+
+  var script = document.currentScript;
+  var base = script ? script.src : location.href;
+  var source = new EventSource(new URL("/__hotreloader/events", base));
+  var overlayId = "__hotreloader-overlay";
+
+  function hideOverlay() {
+    var el = document.getElementById(overlayId);
+    if (el) el.remove();
+  }
+
+  function showOverlay(message) {
+    hideOverlay();
+    var el = document.createElement("div");
+    el.id = overlayId;
+    el.style.cssText =
+      "position:fixed;inset:0;z-index:2147483647;overflow:auto;" +
+      "background:rgba(20,20,20,0.92);color:#eee;padding:2em;" +
+      "font:14px/1.5 monospace;";
+    var title = document.createElement("div");
+    title.textContent = "Build failed";
+    title.style.cssText = "color:#ff6b6b;font-size:20px;margin-bottom:1em;";
+    var pre = document.createElement("pre");
+    pre.textContent = message;
+    pre.style.cssText = "white-space:pre-wrap;margin:0;";
+    el.appendChild(title);
+    el.appendChild(pre);
+    document.body.appendChild(el);
+  }
+
+  function swapStylesheets(path) {
+    var name = path.split("/").pop().replace(/\.[^.]+$/, "");
+    var links = Array.prototype.slice.call(
+      document.querySelectorAll('link[rel="stylesheet"]')
+    );
+    var matching = links.filter(function (link) {
+      return link.href.indexOf(name) !== -1;
+    });
+    // A preprocessed stylesheet may be served under another name
+    (matching.length ? matching : links).forEach(function (link) {
+      var url = new URL(link.href);
+      url.searchParams.set("_hr", Date.now());
+      var next = link.cloneNode();
+      next.href = url.toString();
+      next.onload = function () {
+        link.remove();
+      };
+      link.parentNode.insertBefore(next, link.nextSibling);
+    });
+  }
+
+  source.onmessage = function (event) {
+    var msg = JSON.parse(event.data);
+    switch (msg.type) {
+      case "reload":
+        location.reload();
+        break;
+      case "css":
+        hideOverlay();
+        swapStylesheets(msg.path);
+        break;
+      case "error":
+        showOverlay(msg.message);
+        break;
+    }
+  };
+})();
@@ -0,0 +1,337 @@
+package livereload
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/proxy"
+)
+
+const (
+	// PathPrefix is where the live reload endpoints are served
+	PathPrefix = "/__hotreloader/"
+	// EventsPath is the server-sent events stream browsers subscribe to
+	EventsPath = PathPrefix + "events"
+	// ScriptPath serves the client script
+	ScriptPath = PathPrefix + "client.js"
+	// DefaultListen is the standalone address used when no proxy is configured
+	DefaultListen = ":35729"
+
+	// heartbeatInterval keeps idle connections from being closed by intermediaries
+	heartbeatInterval = 15 * time.Second
+)
+
+//go:embed client.js
+var clientScript []byte
+
+// stylesheetExts are the file types that can be swapped without a reload
+var stylesheetExts = map[string]bool{
+	".css":  true,
+	".scss": true,
+	".sass": true,
+	".less": true,
+}
+
+// Message is sent to connected browsers. Type is "reload", "css" (Path is
+// the changed stylesheet) or "error" (Message is the build error).
+type Message struct {
+	Type    string `json:"type"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Server pushes reload messages to browsers over server-sent events.
+// After a successful build the browser reloads, or swaps stylesheets when
+// only stylesheets changed. When the application is restarted the message
+// is held until the new process is ready. A failed build shows an overlay.
+type Server struct {
+	listen  string
+	server  *http.Server
+	process string
+
+	mu      sync.Mutex
+	clients map[chan Message]struct{}
+
+	// Only touched from HandleEvent, which the bus calls sequentially
+	cssOnly        string
+	managesProcess bool
+	pending        *Message
+}
+
+// NewServer creates a live reload server. listen is the standalone address,
+// which may be empty when the endpoints are only served through the proxy.
+func NewServer(listen string) *Server {
+	return &Server{
+		listen:  listen,
+		clients: make(map[chan Message]struct{}),
+	}
+}
+
+// SetProcess sets the process serving the pages, whose restarts reload
+// the browser. Empty follows every process.
+func (s *Server) SetProcess(name string) {
+	s.process = name
+}
+
+// tracks reports whether events of the named process concern the pages
+func (s *Server) tracks(name string) bool {
+	return s.process == "" || s.process == name
+}
+
+// Start serves the endpoints on the standalone address, if any
+func (s *Server) Start() error {
+	if s.listen == "" {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.listen, err)
+	}
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(l)
+	return nil
+}
+
+// Close stops the standalone server
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Attach serves the endpoints through the proxy and injects the client
+// script into HTML pages passing through it
+func (s *Server) Attach(p *proxy.Proxy) {
+	p.Handle(PathPrefix, s)
+	p.SetPageScript(ScriptTag(""))
+
+	rp := p.ReverseProxy()
+
+	// Ask for uncompressed responses so the script can be injected
+	director := rp.Director
+	rp.Director = func(r *http.Request) {
+		director(r)
+		r.Header.Del("Accept-Encoding")
+	}
+
+	modify := rp.ModifyResponse
+	rp.ModifyResponse = func(resp *http.Response) error {
+		if modify != nil {
+			if err := modify(resp); err != nil {
+				return err
+			}
+		}
+		return InjectScript(resp, ScriptTag(""))
+	}
+}
+
+// ScriptTag returns the script element loading the client from origin,
+// which is empty when the page is served by the same host
+func ScriptTag(origin string) string {
+	return fmt.Sprintf(`<script src="%s%s"></script>`, origin, ScriptPath)
+}
+
+// InjectScript inserts tag before the closing body tag of an uncompressed
+// HTML response. Responses without a body, to HEAD requests or with a 1xx,
+// 204 or 304 status, are left alone.
+func InjectScript(resp *http.Response, tag string) error {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil
+	}
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>")); i >= 0 {
+		body = append(body[:i:i], append([]byte(tag), body[i:]...)...)
+	} else {
+		body = append(body, tag...)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// ServeHTTP serves the event stream and the client script
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The standalone server is on a different origin than the page
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch r.URL.Path {
+	case EventsPath:
+		s.serveEvents(w, r)
+	case ScriptPath:
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(clientScript)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveEvents streams messages to one browser until it disconnects
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ch := make(chan Message, 16)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg := <-ch:
+			data, _ := json.Marshal(msg)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Broadcast sends a message to every connected browser. Browsers that
+// cannot keep up miss the message rather than blocking the sender.
+func (s *Server) Broadcast(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// HandleEvent turns build and process events into browser messages
+func (s *Server) HandleEvent(e events.Event) {
+	switch ev := e.(type) {
+	case events.BuildStarted:
+		s.cssOnly = stylesheetChange(ev.Trigger, ev.AffectedFiles)
+
+	case events.BuildSucceeded:
+		msg := Message{Type: "reload"}
+		if s.cssOnly != "" {
+			msg = Message{Type: "css", Path: s.cssOnly}
+		}
+		if s.managesProcess {
+			// Wait for the restarted process to be ready
+			s.pending = &msg
+			return
+		}
+		s.Broadcast(msg)
+
+	case events.BuildFailed:
+		msg := "build failed"
+		if ev.Err != nil {
+			msg = ev.Err.Error()
+		}
+		s.Broadcast(Message{Type: "error", Message: msg})
+
+	case events.BuildUnchanged:
+		// The running process serves the same code
+		s.pending = nil
+
+	case events.ReloadRequested:
+		// The process was not restarted, so no readiness event follows
+		s.pending = nil
+		msg := Message{Type: "reload"}
+		if css := stylesheetChange(ev.Trigger, nil); css != "" {
+			msg = Message{Type: "css", Path: css}
+		}
+		s.Broadcast(msg)
+
+	case events.ProcessStarted:
+		if s.tracks(ev.Name) {
+			s.managesProcess = true
+		}
+
+	case events.ProcessReady:
+		// Other processes, such as workers, do not serve the pages
+		if !s.tracks(ev.Name) {
+			return
+		}
+		msg := Message{Type: "reload"}
+		if s.pending != nil {
+			msg = *s.pending
+			s.pending = nil
+		}
+		s.Broadcast(msg)
+
+	case events.ProcessStartFailed:
+		if !s.tracks(ev.Name) {
+			return
+		}
+		s.pending = nil
+		msg := "application failed to start"
+		if ev.Err != nil {
+			msg = fmt.Sprintf("%s: %v", msg, ev.Err)
+		}
+		s.Broadcast(Message{Type: "error", Message: msg})
+	}
+}
+
+// stylesheetChange returns the changed stylesheet when the trigger and all
+// affected files are stylesheets, and an empty string otherwise
+func stylesheetChange(trigger string, affected []string) string {
+	if trigger == "" || !stylesheetExts[strings.ToLower(filepath.Ext(trigger))] {
+		return ""
+	}
+	for _, f := range affected {
+		if !stylesheetExts[strings.ToLower(filepath.Ext(f))] {
+			return ""
+		}
+	}
+	return filepath.ToSlash(trigger)
+}
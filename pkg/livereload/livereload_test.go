@@ -0,0 +1,204 @@
+package livereload
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hotreloader/pkg/events"
+)
+
+func TestInjectScript(t *testing.T) {
+	const tag = `<script src="/x.js"></script>`
+
+	tests := []struct {
+		name        string
+		method      string
+		status      int
+		contentType string
+		encoding    string
+		body        string
+		want        string
+	}{
+		{name: "before closing body", body: "<html><BODY>hi</BODY></html>", want: "<html><BODY>hi" + tag + "</BODY></html>"},
+		{name: "without body tag", body: "<p>hi</p>", want: "<p>hi</p>" + tag},
+		{name: "error page", status: http.StatusInternalServerError, body: "<p>oops</p>", want: "<p>oops</p>" + tag},
+		{name: "not html", contentType: "application/json", body: "{}", want: "{}"},
+		{name: "compressed", encoding: "gzip", body: "<p>hi</p>", want: "<p>hi</p>"},
+		{name: "head request", method: http.MethodHead, want: ""},
+		{name: "no content", status: http.StatusNoContent, want: ""},
+		{name: "not modified", status: http.StatusNotModified, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, status, contentType := tt.method, tt.status, tt.contentType
+			if method == "" {
+				method = http.MethodGet
+			}
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if contentType == "" {
+				contentType = "text/html; charset=utf-8"
+			}
+
+			req, _ := http.NewRequest(method, "http://app/", nil)
+			resp := &http.Response{
+				StatusCode:    status,
+				Header:        http.Header{"Content-Type": {contentType}, "Content-Length": {strconv.Itoa(len(tt.body))}},
+				Body:          io.NopCloser(strings.NewReader(tt.body)),
+				ContentLength: int64(len(tt.body)),
+				Request:       req,
+			}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			if err := InjectScript(resp, tag); err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+			if got := resp.Header.Get("Content-Length"); got != strconv.Itoa(len(body)) || resp.ContentLength != int64(len(body)) {
+				t.Errorf("Content-Length = %s (%d), body has %d bytes", got, resp.ContentLength, len(body))
+			}
+		})
+	}
+}
+
+// received returns the messages queued for a client
+func received(ch chan Message) []Message {
+	var msgs []Message
+	for {
+		select {
+		case msg := <-ch:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	reload := Message{Type: "reload"}
+
+	tests := []struct {
+		name    string
+		process string
+		events  []events.Event
+		want    []Message
+	}{
+		{
+			name:   "build without managed process",
+			events: []events.Event{events.BuildStarted{Trigger: "main.go"}, events.BuildSucceeded{}},
+			want:   []Message{reload},
+		},
+		{
+			name: "reload waits for the restarted process",
+			events: []events.Event{
+				events.ProcessStarted{Name: "app", PID: 1},
+				events.BuildStarted{Trigger: "main.go"},
+				events.BuildSucceeded{},
+				events.ProcessReady{Name: "app", PID: 2},
+			},
+			want: []Message{reload},
+		},
+		{
+			name: "stylesheets only",
+			events: []events.Event{
+				events.BuildStarted{Trigger: "web/site.css", AffectedFiles: []string{"web/site.css", "web/theme.SCSS"}},
+				events.BuildSucceeded{},
+			},
+			want: []Message{{Type: "css", Path: "web/site.css"}},
+		},
+		{
+			name: "stylesheet affecting code",
+			events: []events.Event{
+				events.BuildStarted{Trigger: "web/site.css", AffectedFiles: []string{"web/site.css", "web/app.js"}},
+				events.BuildSucceeded{},
+			},
+			want: []Message{reload},
+		},
+		{
+			name: "stylesheet swap waits for the process",
+			events: []events.Event{
+				events.ProcessStarted{Name: "app", PID: 1},
+				events.BuildStarted{Trigger: "site.less"},
+				events.BuildSucceeded{},
+				events.ProcessReady{Name: "app", PID: 2},
+			},
+			want: []Message{{Type: "css", Path: "site.less"}},
+		},
+		{
+			name:   "reload requested for a stylesheet",
+			events: []events.Event{events.ReloadRequested{Trigger: "site.css"}},
+			want:   []Message{{Type: "css", Path: "site.css"}},
+		},
+		{
+			name:   "build failure",
+			events: []events.Event{events.BuildStarted{}, events.BuildFailed{Err: errors.New("syntax error")}},
+			want:   []Message{{Type: "error", Message: "syntax error"}},
+		},
+		{
+			name: "unchanged build",
+			events: []events.Event{
+				events.ProcessStarted{Name: "app", PID: 1},
+				events.BuildStarted{Trigger: "main.go"},
+				events.BuildSucceeded{},
+				events.BuildUnchanged{},
+			},
+		},
+		{
+			name:    "worker ready does not reload",
+			process: "web",
+			events: []events.Event{
+				events.ProcessStarted{Name: "web", PID: 1},
+				events.ProcessStarted{Name: "worker", PID: 2},
+				events.ProcessReady{Name: "worker", PID: 2},
+				events.ProcessStartFailed{Name: "worker", PID: 3, Err: errors.New("probe failed")},
+			},
+		},
+		{
+			name:    "proxied process ready reloads",
+			process: "web",
+			events: []events.Event{
+				events.ProcessStarted{Name: "web", PID: 1},
+				events.ProcessStarted{Name: "worker", PID: 2},
+				events.BuildStarted{Trigger: "main.go"},
+				events.BuildSucceeded{},
+				events.ProcessReady{Name: "worker", PID: 4},
+				events.ProcessReady{Name: "web", PID: 3},
+			},
+			want: []Message{reload},
+		},
+		{
+			name:    "proxied process fails to start",
+			process: "web",
+			events:  []events.Event{events.ProcessStartFailed{Name: "web", PID: 3, Err: errors.New("probe failed")}},
+			want:    []Message{{Type: "error", Message: "application failed to start: probe failed"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("")
+			s.SetProcess(tt.process)
+			ch := make(chan Message, 16)
+			s.clients[ch] = struct{}{}
+
+			for _, e := range tt.events {
+				s.HandleEvent(e)
+			}
+			if got := received(ch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package optimizer
+
+import (
+	"time"
+
+	"hotreloader/pkg/cache"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/pattern"
+)
+
+// maxExplanations is the number of recent rebuild explanations kept
+const maxExplanations = 50
+
+// explain records and publishes why a change caused a rebuild and through
+// which dependency chains the affected files were reached
+func (o *Optimizer) explain(filePath string, reason cache.MissReason, chains [][]string) {
+	e := events.Explanation{
+		Trigger:  pattern.Rel(o.projectDir, filePath),
+		Time:     time.Now(),
+		Reason:   string(reason),
+		Affected: make([]events.AffectedFile, 0, len(chains)),
+	}
+	for _, chain := range chains {
+		rel := make([]string, len(chain))
+		var kinds []string
+		for i, f := range chain {
+			rel[i] = pattern.Rel(o.projectDir, f)
+			if i > 0 {
+				kinds = append(kinds, o.depGraph.EdgeKind(f, chain[i-1]))
+			}
+		}
+		e.Affected = append(e.Affected, events.AffectedFile{File: rel[len(rel)-1], Chain: rel, Kinds: kinds})
+	}
+
+	o.explainMu.Lock()
+	o.explanations = append(o.explanations, e)
+	if len(o.explanations) > maxExplanations {
+		o.explanations = o.explanations[len(o.explanations)-maxExplanations:]
+	}
+	o.explainMu.Unlock()
+
+	o.bus.Publish(events.RebuildExplained{Meta: events.Now(), Explanation: e})
+}
+
+// Explanations returns the recent rebuild explanations, newest first
+func (o *Optimizer) Explanations() []events.Explanation {
+	o.explainMu.Lock()
+	defer o.explainMu.Unlock()
+
+	result := make([]events.Explanation, len(o.explanations))
+	for i, e := range o.explanations {
+		result[len(result)-1-i] = e
+	}
+	return result
+}
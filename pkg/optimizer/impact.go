@@ -0,0 +1,80 @@
+package optimizer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/pattern"
+)
+
+// goGraphFor returns the package graph of the project, brought up to date
+// with a changed file, or as cached when filePath is empty. A changed Go
+// file only reads its own package again; the graph is loaded again when
+// go.mod changes or a directory is added or removed. The graph is for the
+// host with the configured tags, as the application is built and its
+// tests are run here whatever target the files are analyzed for.
+func (o *Optimizer) goGraphFor(filePath string) (*analyzer.GoGraph, error) {
+	o.goGraphMu.Lock()
+	defer o.goGraphMu.Unlock()
+
+	if o.goGraph != nil && filePath != "" {
+		info, err := os.Stat(filePath)
+		switch {
+		case filepath.Base(filePath) == "go.mod":
+			o.goGraph = nil
+		case filepath.Ext(filePath) == ".go":
+			o.goGraph.Update(filepath.Dir(filePath))
+		case err == nil && info.IsDir():
+			o.goGraph = nil
+		case os.IsNotExist(err) && filepath.Ext(filePath) == "":
+			// Most likely a removed directory
+			o.goGraph = nil
+		}
+	}
+
+	if o.goGraph == nil {
+		graph, err := analyzer.LoadGoGraph(o.projectDir, o.hostTarget)
+		if err != nil {
+			return nil, err
+		}
+		o.goGraph = graph
+	}
+	return o.goGraph, nil
+}
+
+// unlinked reports whether a changed Go file cannot change the application
+// binary, and why: it is excluded by build constraints for the host, is a
+// test file, or is in a package the application does not import
+func (o *Optimizer) unlinked(filePath string) (string, bool) {
+	active := o.pluginMgr.GetActivePlugin()
+	if o.app == nil || active == nil || active.Name() != "go" || filepath.Ext(filePath) != ".go" {
+		return "", false
+	}
+
+	graph, err := o.goGraphFor(filePath)
+	if err != nil {
+		return "", false
+	}
+	app, ok := graph.PackageInDir(o.projectDir)
+	if !ok || !app.IsCommand() {
+		return "", false
+	}
+	if _, ok := graph.PackageOf(filePath); !ok {
+		return "", false
+	}
+
+	impact := graph.Impact(filePath)
+	rel := pattern.Rel(o.projectDir, filePath)
+	switch {
+	case slices.Contains(impact.Commands, app.ImportPath):
+		return "", false
+	case len(impact.Packages) == 0 && len(impact.Tests) > 0:
+		return rel + " is a test file", true
+	case len(impact.Packages) == 0:
+		return rel + " is excluded by build constraints for " + o.hostTarget.GOOS + "/" + o.hostTarget.GOARCH, true
+	default:
+		return "the application does not import " + impact.Packages[0], true
+	}
+}
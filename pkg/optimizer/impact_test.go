@@ -0,0 +1,113 @@
+package optimizer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/config"
+)
+
+func TestGoGraphForCaches(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.22\n",
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"lib/lib.go": "package lib\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(dir, "newdir"), 0755)
+
+	tests := []struct {
+		name   string
+		file   string
+		reload bool
+	}{
+		{name: "go file", file: "lib/lib.go"},
+		{name: "go.sum", file: "go.sum"},
+		{name: "other file", file: "README.md"},
+		{name: "go.mod", file: "go.mod", reload: true},
+		{name: "new directory", file: "newdir", reload: true},
+		{name: "removed directory", file: "gone", reload: true},
+	}
+
+	o := &Optimizer{projectDir: dir, hostTarget: analyzer.NewGoTarget("", "", nil)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := o.goGraphFor("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := o.goGraphFor(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reloaded := before != after; reloaded != tt.reload {
+				t.Errorf("graph reloaded = %v, want %v", reloaded, tt.reload)
+			}
+		})
+	}
+}
+
+func TestUnlinkedUsesHostGraph(t *testing.T) {
+	// The analysis target is another platform than the one builds run on
+	host := runtime.GOOS
+	other := "windows"
+	if host == other {
+		other = "linux"
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module example.com/m\n\ngo 1.22\n",
+		"main.go":              "package main\n\nfunc main() { run() }\n",
+		"run_" + host + ".go":  "package main\n\nfunc run() {}\n",
+		"run_" + other + ".go": "package main\n\nfunc run() {}\n",
+		"main_test.go":         "package main\n",
+		"tagged.go":            "//go:build special\n\npackage main\n",
+		"tools/tool.go":        "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Go = config.GoConfig{GOOS: other, Tags: []string{"special"}}
+	o := NewOptimizer(dir, cfg)
+	if o.pluginMgr.GetActivePlugin() == nil {
+		t.Skip("go not available")
+	}
+
+	tests := []struct {
+		file       string
+		wantReason string
+	}{
+		{file: "run_" + host + ".go"},
+		{file: "tagged.go"},
+		{file: "run_" + other + ".go", wantReason: "excluded by build constraints for " + host + "/"},
+		{file: "main_test.go", wantReason: "is a test file"},
+		{file: "tools/tool.go", wantReason: "does not import"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			reason, skip := o.unlinked(filepath.Join(dir, tt.file))
+			if skip != (tt.wantReason != "") || !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("unlinked(%s) = %q, %v, want reason %q", tt.file, reason, skip, tt.wantReason)
+			}
+		})
+	}
+}
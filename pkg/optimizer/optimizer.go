@@ -1,31 +1,74 @@
 package optimizer
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
 	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/artifact"
 	"hotreloader/pkg/cache"
+	"hotreloader/pkg/config"
 	"hotreloader/pkg/dashboard"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/hooks"
+	"hotreloader/pkg/output"
+	"hotreloader/pkg/pipeline"
 	"hotreloader/pkg/plugin"
+	"hotreloader/pkg/process"
+	"hotreloader/pkg/readiness"
+	"hotreloader/pkg/testrunner"
 )
 
 // Optimizer is the core hot reload optimizer
 type Optimizer struct {
-	cache          *cache.ModuleCache
-	analyzer       *analyzer.DependencyAnalyzer
-	depGraph       *analyzer.DependencyGraph
-	dashboard      *dashboard.Dashboard
-	mu             sync.RWMutex
-	stats          *BuildStats
-	pluginMgr      *plugin.PluginManager
-	currentProcess *exec.Cmd
-	processMu      sync.Mutex
-	outputBinary   string
-	projectDir     string
+	cache        *cache.ModuleCache
+	analyzer     *analyzer.DependencyAnalyzer
+	depGraph     *analyzer.DependencyGraph
+	hostTarget   analyzer.GoTarget
+	goGraphMu    sync.Mutex
+	goGraph      *analyzer.GoGraph
+	dashboard    *dashboard.Dashboard
+	bus          *events.Bus
+	mu           sync.RWMutex
+	stats        *BuildStats
+	pluginMgr    *plugin.PluginManager
+	hooks        *hooks.Runner
+	artifacts    *artifact.Store
+	gracePeriod  time.Duration
+	autoRollback bool
+	restart      restartPolicy
+	procCtl      *process.Controller
+	output       *output.Mux
+	app          *service
+	services     []*service
+	tests        *testrunner.Runner
+	coverage     *coverage
+	pipeline     *pipeline.Pipeline
+	rules        []*rule
+	explainMu    sync.Mutex
+	explanations []events.Explanation
+	strategies   []*strategy
+	processMu    sync.Mutex
+	outputBinary string
+	projectDir   string
+}
+
+// appProcess is a started process, its service and the build it runs
+type appProcess struct {
+	*process.Process
+	svc        *service
+	build      *artifact.Build
+	checkGrace bool
+	stdout     *output.Writer
+	stderr     *output.Writer
+
+	// ready is closed once the readiness probes pass
+	ready chan struct{}
 }
 
 // BuildStats tracks rebuild statistics
@@ -39,29 +82,117 @@ type BuildStats struct {
 }
 
 // NewOptimizer creates a new optimizer instance
-func NewOptimizer(projectDir string) *Optimizer {
+func NewOptimizer(projectDir string, cfg *config.Config) *Optimizer {
 	// Initialize plugin manager
 	pluginMgr := plugin.NewPluginManager()
 
 	// Register available plugins
-	pluginMgr.Register(plugin.NewGoPlugin(projectDir))
+	goPlugin := plugin.NewGoPlugin(projectDir)
+	// The target only selects the files analyzed; the build must run here
+	goPlugin.SetTags(cfg.Go.Tags)
+	pluginMgr.Register(goPlugin)
 	pluginMgr.Register(plugin.NewWebpackPlugin("webpack.config.js"))
 	pluginMgr.Register(plugin.NewVitePlugin("vite.config.js"))
 
-	// Try to detect and activate a plugin
-	if err := pluginMgr.DetectAndActivate(); err != nil {
+	// Named processes build what they run, so no build plugin is used
+	if cfg.Mode == config.ModeTest {
+		fmt.Println("Running affected Go tests instead of restarting the application")
+	} else if len(cfg.Processes) > 0 {
+		fmt.Printf("Managing %d processes\n", len(cfg.Processes))
+	} else if err := pluginMgr.DetectAndActivate(); err != nil {
 		fmt.Printf("Warning: No build plugin detected: %v\n", err)
 		fmt.Println("Hot reloader will run in analysis-only mode")
 	} else {
 		fmt.Printf("Detected build tool: %s\n", pluginMgr.GetActivePlugin().Name())
 	}
 
+	// Keep versioned build artifacts for rollback
+	artifacts, err := artifact.NewStore(config.BuildsDir(projectDir), cfg.Artifacts.Keep)
+	if err != nil {
+		fmt.Printf("Warning: build artifacts will not be retained: %v\n", err)
+		artifacts = nil
+	}
+
+	// Config validation already rejected malformed sequences
+	stopSequence, err := process.ParseStopSequence(cfg.StopSequence)
+	if err != nil {
+		stopSequence = process.MustParseStopSequence(process.DefaultStopSequence)
+	}
+	procCtl := process.NewController(stopSequence)
+
+	// Own the listening sockets so restarts can hand them off
+	if len(cfg.Listeners) > 0 && len(cfg.Processes) > 0 {
+		fmt.Println("Warning: listeners are only handed to the built application, ignoring them")
+	} else if len(cfg.Listeners) > 0 {
+		if err := procCtl.Listen(cfg.Listeners); err != nil {
+			fmt.Printf("Warning: listener hand-off disabled: %v\n", err)
+		} else {
+			fmt.Printf("Listening on %s (passed to the application as LISTEN_FDS)\n", strings.Join(cfg.Listeners, ", "))
+		}
+	}
+
+	// The dashboard and console log are independent event consumers
+	out := output.NewMux(cfg.Output, config.LogsDir(projectDir))
+	dash := dashboard.NewDashboard()
+	dash.SetOutput(out)
+	bus := events.NewBus()
+	bus.Subscribe("dashboard", dash, events.DefaultQueueSize)
+	bus.Subscribe("console", events.NewConsoleLogger(), events.DefaultQueueSize)
+
+	var app *service
+	var services []*service
+	var tests *testrunner.Runner
+	var cov *coverage
+	var pipe *pipeline.Pipeline
+	if cfg.Mode == config.ModeTest {
+		tests = testrunner.NewRunner(projectDir, cfg.Test)
+		tests.SetTags(cfg.Go.Tags)
+		if cfg.Test.Coverage {
+			cov = newCoverage(projectDir)
+		}
+	} else {
+		app, services = newServices(cfg, procCtl, process.NewController(stopSequence))
+		if app == nil {
+			fmt.Printf("Processes: %s\n", serviceNames(services))
+		}
+		if len(cfg.Pipeline) > 0 {
+			pipe = pipeline.New(projectDir, cfg.Pipeline)
+			fmt.Printf("Pipeline: %s\n", pipe)
+		}
+	}
+
+	// Cache keys cover the files each file depends on in the graph
+	depGraph := analyzer.NewDependencyGraph()
+	moduleCache := cache.NewModuleCache()
+	moduleCache.SetResolver(depGraph.ResolveDependencies)
+
+	goTarget := analyzer.NewGoTarget(cfg.Go.GOOS, cfg.Go.GOARCH, cfg.Go.Tags)
+	depAnalyzer := analyzer.NewDependencyAnalyzer()
+	depAnalyzer.SetGoTarget(goTarget)
+	depAnalyzer.SetPythonRoots(analyzer.PythonSourceRoots(projectDir, cfg.Python.SourceRoots))
+
 	return &Optimizer{
-		cache:        cache.NewModuleCache(),
-		analyzer:     analyzer.NewDependencyAnalyzer(),
-		depGraph:     analyzer.NewDependencyGraph(),
-		dashboard:    dashboard.NewDashboard(),
+		cache:        moduleCache,
+		analyzer:     depAnalyzer,
+		depGraph:     depGraph,
+		hostTarget:   analyzer.NewGoTarget("", "", cfg.Go.Tags),
+		dashboard:    dash,
+		bus:          bus,
 		pluginMgr:    pluginMgr,
+		hooks:        hooks.NewRunner(projectDir, cfg.Hooks),
+		artifacts:    artifacts,
+		gracePeriod:  cfg.Artifacts.GracePeriod.Duration,
+		autoRollback: cfg.Artifacts.AutoRollback,
+		restart:      newRestartPolicy(cfg.Restart),
+		procCtl:      procCtl,
+		output:       out,
+		app:          app,
+		services:     services,
+		tests:        tests,
+		coverage:     cov,
+		pipeline:     pipe,
+		rules:        newRules(cfg.Rules),
+		strategies:   newStrategies(cfg.RestartStrategies),
 		outputBinary: "/tmp/hotreload_output",
 		projectDir:   projectDir,
 		stats: &BuildStats{
@@ -76,21 +207,39 @@ func (o *Optimizer) ProcessFileChange(filePath string) error {
 	defer o.mu.Unlock()
 
 	startTime := time.Now()
+	o.bus.Publish(events.FileChanged{Meta: events.Now(), Path: filePath})
+
+	// Keys computed before the change no longer hold for the file and the
+	// files depending on it
+	o.cache.Changed(filePath)
 
 	// Check if file is in cache and still valid
-	valid, err := o.cache.IsValid(filePath)
+	reason, err := o.cache.Check(filePath)
 	if err != nil {
 		return fmt.Errorf("error checking cache: %w", err)
 	}
 
-	if valid {
+	if reason == "" {
 		o.stats.mu.Lock()
 		o.stats.CacheHits++
 		o.stats.mu.Unlock()
-		o.dashboard.UpdateCacheHit(filePath)
+		o.bus.Publish(events.CacheHit{Meta: events.Now(), Path: filePath})
 		return nil
 	}
 
+	// Rules handle matching files before the build plugin
+	if r := o.matchRule(filePath); r != nil {
+		if err := o.applyRule(r, filePath); err != nil {
+			return err
+		}
+		if !r.build {
+			if err := o.cache.UpdateCache(filePath, nil); err != nil {
+				return fmt.Errorf("error updating cache: %w", err)
+			}
+			return nil
+		}
+	}
+
 	// Cache miss - need to rebuild
 	o.stats.mu.Lock()
 	o.stats.CacheMisses++
@@ -98,61 +247,94 @@ func (o *Optimizer) ProcessFileChange(filePath string) error {
 	o.stats.mu.Unlock()
 
 	// Analyze dependencies
-	deps, err := o.analyzer.AnalyzeDependencies(filePath)
+	edges, err := o.analyzer.AnalyzeEdges(filePath)
 	if err != nil {
 		return fmt.Errorf("error analyzing dependencies: %w", err)
 	}
+	deps := make([]string, len(edges))
+	for i, e := range edges {
+		deps[i] = e.Target
+	}
 
 	// Update dependency graph
-	o.depGraph.AddDependency(filePath, deps)
-
-	// Get all affected files (files that depend on this one)
-	affectedFiles := o.depGraph.GetAllAffectedFiles(filePath)
+	o.depGraph.AddEdges(filePath, edges)
+
+	// Get all affected files (files that depend on this one), plus cached
+	// files whose key differs because something they depend on changed
+	chains := o.depGraph.GetAffectedChains(filePath)
+	affected := make(map[string]bool, len(chains))
+	for _, chain := range chains {
+		affected[chain[len(chain)-1]] = true
+	}
+	for _, file := range o.cache.Stale() {
+		if !affected[file] {
+			affected[file] = true
+			chains = append(chains, []string{file})
+		}
+	}
+	affectedFiles := make([]string, len(chains))
+	for i, chain := range chains {
+		affectedFiles[i] = chain[len(chain)-1]
+	}
+	o.explain(filePath, reason, chains)
 
-	// Invalidate cache for affected files
 	rebuildStart := time.Now()
-	for _, file := range affectedFiles {
-		o.cache.Invalidate(file)
+
+	hctx := hooks.Context{
+		ChangedFiles:  []string{filePath},
+		AffectedCount: len(affectedFiles),
 	}
 
 	// ACTUAL BUILD: Run the build plugin if available
-	if o.pluginMgr.GetActivePlugin() != nil {
-		fmt.Printf("\n🔨 Building (affected files: %d)...\n", len(affectedFiles))
+	if o.tests != nil {
+		if err := o.runAffectedTests(filePath); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	} else if o.pipeline != nil {
+		if err := o.runPipeline(filePath, affectedFiles, hctx, rebuildStart); err != nil {
+			return err
+		}
+	} else if reason, ok := o.unlinked(filePath); ok {
+		fmt.Printf("⏭️  Skipping build: %s\n", reason)
+	} else if o.pluginMgr.GetActivePlugin() != nil {
+		build, err := o.build(filePath, affectedFiles, &hctx, rebuildStart)
+		if err != nil {
+			return err
+		}
 
-		buildStart := time.Now()
-		if err := o.pluginMgr.Build(affectedFiles); err != nil {
-			fmt.Printf("❌ Build failed: %v\n", err)
-			return fmt.Errorf("build failed: %w", err)
+		if err := o.hooks.Run(hooks.PostBuild, hctx); err != nil {
+			fmt.Printf("⛔ Restart skipped: %v\n", err)
+		} else if o.pluginMgr.GetActivePlugin().Name() == "go" && !o.skipUnchanged(o.app, filePath, build) {
+			// Only restart if using Go plugin (compiled binaries)
+			o.restartService(o.app, hctx, build, o.strategyFor(filePath), false)
 		}
-		buildDuration := time.Since(buildStart)
-		fmt.Printf("✅ Build successful (took %v)\n", buildDuration)
-
-		// Only restart if using Go plugin (compiled binaries)
-		if o.pluginMgr.GetActivePlugin().Name() == "go" {
-			fmt.Println("🔄 Restarting application...")
-			if err := o.restartProcess(); err != nil {
-				fmt.Printf("⚠️  Failed to restart process: %v\n", err)
-			} else {
-				fmt.Println("✅ Application restarted successfully")
-			}
+	} else {
+		// Analysis-only mode: the rebuild is the cache invalidation
+		o.bus.Publish(events.BuildSucceeded{
+			Meta:          events.Now(),
+			Trigger:       filePath,
+			AffectedCount: len(affectedFiles),
+			Duration:      time.Since(rebuildStart),
+		})
+
+		if o.app == nil {
+			o.restartAffected(hctx, affectedFiles)
 		}
 	}
 
-	// Update cache for the changed file
+	// Update cache for the changed file, and the keys of the affected files
+	// now that they were rebuilt against it
 	if err := o.cache.UpdateCache(filePath, deps); err != nil {
 		return fmt.Errorf("error updating cache: %w", err)
 	}
+	o.cache.Refresh(affectedFiles[1:])
 
-	duration := time.Since(rebuildStart)
 	totalDuration := time.Since(startTime)
 
 	o.stats.mu.Lock()
 	o.stats.LastRebuildTime = totalDuration
 	o.stats.mu.Unlock()
 
-	// Update dashboard
-	o.dashboard.UpdateRebuild(filePath, len(affectedFiles), duration)
-
 	return nil
 }
 
@@ -177,6 +359,11 @@ func (o *Optimizer) GetStats() *BuildStats {
 	return statsCopy
 }
 
+// Events returns the event bus so integrations can subscribe to it
+func (o *Optimizer) Events() *events.Bus {
+	return o.bus
+}
+
 // GetDashboard returns the dashboard instance
 func (o *Optimizer) GetDashboard() *dashboard.Dashboard {
 	return o.dashboard
@@ -217,113 +404,426 @@ func (o *Optimizer) AnalyzeProject(rootDir string) error {
 
 // InitialBuild performs the first build and starts the application
 func (o *Optimizer) InitialBuild() error {
+	if o.tests != nil {
+		return o.loadTestGraph()
+	}
+	if o.pipeline != nil {
+		return o.runPipeline("", nil, hooks.Context{}, time.Now())
+	}
+	if o.app == nil {
+		return o.startServices()
+	}
+
 	if o.pluginMgr.GetActivePlugin() == nil {
 		fmt.Println("No build plugin available, skipping initial build")
 		return nil
 	}
 
-	fmt.Println("\n🔨 Performing initial build...")
+	hctx := hooks.Context{}
+	if err := o.hooks.Run(hooks.PreBuild, hctx); err != nil {
+		fmt.Printf("⛔ Initial build skipped: %v\n", err)
+		return fmt.Errorf("initial build vetoed: %w", err)
+	}
+
+	active := o.pluginMgr.GetActivePlugin()
+	o.bus.Publish(events.BuildStarted{Meta: events.Now(), Plugin: active.Name()})
 	buildStart := time.Now()
 
 	// Build the project
-	if err := o.pluginMgr.Build([]string{}); err != nil {
-		fmt.Printf("❌ Initial build failed: %v\n", err)
+	build, err := o.buildArtifact([]string{}, "")
+	if err != nil {
+		hctx.Duration = time.Since(buildStart)
+		hctx.Err = err
+		o.bus.Publish(events.BuildFailed{
+			Meta:     events.Now(),
+			Plugin:   active.Name(),
+			Duration: hctx.Duration,
+			Err:      err,
+		})
+		o.hooks.Run(hooks.OnBuildFailure, hctx)
 		return fmt.Errorf("initial build failed: %w", err)
 	}
 
 	buildDuration := time.Since(buildStart)
-	fmt.Printf("✅ Initial build successful (took %v)\n", buildDuration)
+	o.bus.Publish(events.BuildSucceeded{
+		Meta:          events.Now(),
+		Plugin:        active.Name(),
+		Duration:      buildDuration,
+		BuildDuration: buildDuration,
+	})
+
+	hctx.Duration = buildDuration
+	if err := o.hooks.Run(hooks.PostBuild, hctx); err != nil {
+		fmt.Printf("⛔ Start skipped: %v\n", err)
+		return fmt.Errorf("start vetoed: %w", err)
+	}
 
 	// Start the process if it's a Go project
-	if o.pluginMgr.GetActivePlugin().Name() == "go" {
-		fmt.Println("▶️  Starting application...")
-		if err := o.restartProcess(); err != nil {
-			fmt.Printf("⚠️  Failed to start process: %v\n", err)
+	if active.Name() == "go" {
+		if err := o.restartService(o.app, hctx, build, nil, true); err != nil {
 			return fmt.Errorf("failed to start process: %w", err)
 		}
-		fmt.Println("✅ Application started successfully\n")
 	}
 
 	return nil
 }
 
-// restartProcess stops the current process and starts a new one
-func (o *Optimizer) restartProcess() error {
+// build runs the pre-build hooks and the active plugin for a change,
+// publishing the build events. hctx receives the build duration or error.
+func (o *Optimizer) build(trigger string, affectedFiles []string, hctx *hooks.Context, rebuildStart time.Time) (*artifact.Build, error) {
+	active := o.pluginMgr.GetActivePlugin()
+	if err := o.hooks.Run(hooks.PreBuild, *hctx); err != nil {
+		fmt.Printf("⛔ Build skipped: %v\n", err)
+		return nil, fmt.Errorf("build vetoed: %w", err)
+	}
+
+	o.bus.Publish(events.BuildStarted{
+		Meta:          events.Now(),
+		Trigger:       trigger,
+		Plugin:        active.Name(),
+		AffectedFiles: affectedFiles,
+	})
+
+	buildStart := time.Now()
+	build, err := o.buildArtifact(affectedFiles, trigger)
+	if err != nil {
+		hctx.Duration = time.Since(buildStart)
+		hctx.Err = err
+		o.bus.Publish(events.BuildFailed{
+			Meta:          events.Now(),
+			Trigger:       trigger,
+			Plugin:        active.Name(),
+			AffectedCount: len(affectedFiles),
+			Duration:      hctx.Duration,
+			Err:           err,
+		})
+		o.hooks.Run(hooks.OnBuildFailure, *hctx)
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	buildDuration := time.Since(buildStart)
+	o.bus.Publish(events.BuildSucceeded{
+		Meta:          events.Now(),
+		Trigger:       trigger,
+		Plugin:        active.Name(),
+		AffectedCount: len(affectedFiles),
+		Duration:      time.Since(rebuildStart),
+		BuildDuration: buildDuration,
+	})
+
+	hctx.Duration = buildDuration
+	return build, nil
+}
+
+// skipUnchanged reports whether a build produced the same binary as the one
+// the service is running, in which case the duplicate artifact is dropped
+// and a no-op build is recorded instead of restarting
+func (o *Optimizer) skipUnchanged(svc *service, trigger string, build *artifact.Build) bool {
+	if build == nil || build.Hash == "" {
+		return false
+	}
+
+	o.processMu.Lock()
+	proc := svc.current
+	o.processMu.Unlock()
+	if proc == nil || proc.Exited() || proc.build == nil || proc.build.Hash != build.Hash {
+		return false
+	}
+
+	o.artifacts.Remove(build.ID)
+	o.bus.Publish(events.BuildUnchanged{
+		Meta:    events.Now(),
+		Trigger: trigger,
+		Name:    svc.name,
+		PID:     proc.Pid(),
+	})
+	return true
+}
+
+// buildArtifact runs the active plugin. Plugins that produce a binary write
+// it to a fresh artifact slot, which is kept once the build succeeds.
+func (o *Optimizer) buildArtifact(files []string, trigger string) (*artifact.Build, error) {
+	ap, ok := o.pluginMgr.GetActivePlugin().(plugin.ArtifactPlugin)
+	if !ok || o.artifacts == nil {
+		return nil, o.pluginMgr.Build(files)
+	}
+
+	id, path := o.artifacts.Reserve()
+	ap.SetOutputPath(path)
+	if err := o.pluginMgr.Build(files); err != nil {
+		o.artifacts.Discard(path)
+		return nil, err
+	}
+
+	return o.artifacts.Commit(id, path, trigger), nil
+}
+
+// restartProcess stops the current process of a service and starts the
+// given build, or with overlap starts the build before stopping the old
+// process. The pre-restart hooks may veto the restart, leaving the old
+// process running. If the new process fails its readiness probes or exits
+// within the grace period, the last good build is started instead.
+func (o *Optimizer) restartProcess(svc *service, hctx hooks.Context, build *artifact.Build, overlap bool) error {
 	o.processMu.Lock()
 	defer o.processMu.Unlock()
 
-	// Kill old process if it exists
-	if o.currentProcess != nil && o.currentProcess.Process != nil {
-		fmt.Printf("Stopping old process (PID: %d)...\n", o.currentProcess.Process.Pid)
-
-		// Try graceful shutdown first
-		if err := o.currentProcess.Process.Signal(os.Interrupt); err == nil {
-			// Wait up to 2 seconds for graceful shutdown
-			done := make(chan error)
-			go func() {
-				done <- o.currentProcess.Wait()
-			}()
-
-			select {
-			case <-done:
-				fmt.Println("Process stopped gracefully")
-			case <-time.After(2 * time.Second):
-				// Force kill if graceful shutdown times out
-				fmt.Println("Graceful shutdown timed out, force killing...")
-				o.currentProcess.Process.Kill()
-				o.currentProcess.Wait()
-			}
+	hctx.Process = svc.name
+	if svc.current != nil {
+		hctx.PID = svc.current.Pid()
+	}
+	if err := o.hooks.Run(hooks.PreRestart, hctx); err != nil {
+		return fmt.Errorf("restart vetoed: %w", err)
+	}
+
+	// A new build gets a fresh crash-loop budget
+	svc.crashes = nil
+
+	if old := svc.current; old != nil && (overlap || svc.ctl.HandsOff()) {
+		if svc.ctl.HandsOff() {
+			fmt.Printf("🤝 Handing off listeners from PID %d...\n", old.Pid())
 		} else {
-			// If interrupt fails, just kill it
-			o.currentProcess.Process.Kill()
-			o.currentProcess.Wait()
+			fmt.Printf("🔀 Starting the new build next to PID %d...\n", old.Pid())
+		}
+		proc, err := o.handOffLocked(svc, old, build)
+		if err != nil {
+			return err
+		}
+		hctx.PID = proc.Pid()
+		if err := o.hooks.Run(hooks.PostRestart, hctx); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+		return nil
+	}
+
+	o.stopProcessLocked(svc)
+
+	proc, err := o.startProcessLocked(svc, build, true)
+	if err != nil {
+		if o.autoRollback && build != nil {
+			if rbErr := o.rollbackLocked(svc, build); rbErr != nil {
+				fmt.Printf("⚠️  Rollback failed: %v\n", rbErr)
+			}
 		}
+		return err
 	}
 
-	// Start new process
-	cmd := exec.Command(o.outputBinary)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	hctx.PID = proc.Pid()
+	if err := o.hooks.Run(hooks.PostRestart, hctx); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	return nil
+}
+
+// handOffLocked starts the build next to the running process. Both share the
+// inherited listening sockets, or bind with SO_REUSEPORT, so the old process
+// keeps serving until the new one is ready and is only then told to drain
+// and stop. If the new process
+// never becomes ready it is stopped and the old one stays current.
+func (o *Optimizer) handOffLocked(svc *service, old *appProcess, build *artifact.Build) (*appProcess, error) {
+	// Detach the old process so its supervisor ignores its exit
+	svc.gen++
+	svc.current = nil
+
+	proc, err := o.startProcessLocked(svc, build, true)
+	if err != nil {
+		o.stopProcessLocked(svc)
+
+		if !old.Exited() {
+			svc.current = old
+			fmt.Printf("↩️  Keeping previous process (PID: %d)\n", old.Pid())
+			return nil, err
+		}
+
+		// The old process died while detached; fall back to the last good build
+		o.publishExit(old, true)
+		if o.autoRollback && build != nil {
+			if rbErr := o.rollbackLocked(svc, build); rbErr != nil {
+				fmt.Printf("⚠️  Rollback failed: %v\n", rbErr)
+			}
+		}
+		return nil, err
+	}
+
+	o.bus.Publish(events.ProcessStopping{Meta: events.Now(), Name: svc.name, PID: old.Pid()})
+	svc.ctl.Stop(old.Process)
+	o.publishExit(old, false)
+	return proc, nil
+}
+
+// startProcessLocked starts a service under supervision and waits for its
+// readiness probes. The app service runs the given build, named services
+// run their command. The process stays current even if the probes fail.
+// checkGrace enables the rollback when the process exits within the grace period.
+func (o *Optimizer) startProcessLocked(svc *service, build *artifact.Build, checkGrace bool) (*appProcess, error) {
+	// Fresh probes for this start; log probes need a copy of the output
+	checker, err := readiness.NewChecker(svc.readiness)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if svc.command != "" {
+		cmd = exec.Command("sh", "-c", svc.command)
+	} else {
+		binary := o.outputBinary
+		if build != nil {
+			binary = build.Path
+		}
+		cmd = exec.Command(binary)
+	}
+
+	stdout, stderr := o.output.Writers(svc.name)
+	cmd.Stdout = io.MultiWriter(append([]io.Writer{stdout}, checker.OutputWriters()...)...)
+	cmd.Stderr = io.MultiWriter(append([]io.Writer{stderr}, checker.OutputWriters()...)...)
 	cmd.Dir = o.projectDir
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start process: %w", err)
+	startTime := time.Now()
+	started, err := svc.ctl.Start(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start process: %w", err)
+	}
+
+	proc := &appProcess{
+		Process:    started,
+		svc:        svc,
+		build:      build,
+		checkGrace: checkGrace,
+		stdout:     stdout,
+		stderr:     stderr,
+		ready:      make(chan struct{}),
+	}
+
+	svc.current = proc
+	svc.gen++
+	go o.supervise(proc)
+	o.bus.Publish(events.ProcessStarted{Meta: events.Now(), Name: svc.name, PID: started.Pid()})
+
+	// The process only counts as started once its probes pass
+	if !checker.Empty() {
+		fmt.Printf("⏳ Waiting for %s to become ready...\n", svc.label())
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-started.Done():
+			cancel(fmt.Errorf("process exited before becoming ready (%s)", exitDescription(started)))
+		case <-ctx.Done():
+		}
+	}()
+	if err := checker.Wait(ctx); err != nil {
+		o.bus.Publish(events.ProcessStartFailed{
+			Meta: events.Now(),
+			Name: svc.name,
+			PID:  started.Pid(),
+			Err:  err,
+		})
+		return nil, err
+	}
+	close(proc.ready)
+	o.bus.Publish(events.ProcessReady{
+		Meta:     events.Now(),
+		Name:     svc.name,
+		PID:      started.Pid(),
+		Duration: time.Since(startTime),
+	})
+
+	return proc, nil
+}
+
+// stopProcessLocked stops the current process of a service and everything
+// it forked using the configured stop sequence
+func (o *Optimizer) stopProcessLocked(svc *service) {
+	// Cancels any restart the supervisor has scheduled
+	svc.gen++
+
+	proc := svc.current
+	if proc == nil {
+		return
 	}
+	svc.current = nil
 
-	o.currentProcess = cmd
-	fmt.Printf("✅ Started new process with PID: %d\n", cmd.Process.Pid)
+	o.bus.Publish(events.ProcessStopping{Meta: events.Now(), Name: svc.name, PID: proc.Pid()})
+	svc.ctl.Stop(proc.Process)
+	o.publishExit(proc, false)
+}
 
+// rollbackLocked replaces a failed build with the last good one
+func (o *Optimizer) rollbackLocked(svc *service, failed *artifact.Build) error {
+	good, ok := o.artifacts.LastGood(failed.ID)
+	if !ok {
+		return fmt.Errorf("no earlier good build to roll back to")
+	}
+
+	fmt.Printf("⏪ Rolling back from build #%d to last good build #%d...\n", failed.ID, good.ID)
+	o.stopProcessLocked(svc)
+
+	if _, err := o.startProcessLocked(svc, good, false); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Rolled back to build #%d\n", good.ID)
 	return nil
 }
 
-// Shutdown gracefully stops the current running process
+// Builds returns the retained build artifacts, newest first
+func (o *Optimizer) Builds() []artifact.Build {
+	if o.artifacts == nil {
+		return nil
+	}
+	return o.artifacts.List()
+}
+
+// Rollback stops the current process and starts an earlier build by hand
+func (o *Optimizer) Rollback(id int) error {
+	if o.artifacts == nil {
+		return fmt.Errorf("build artifacts are not retained")
+	}
+	if o.app == nil {
+		return fmt.Errorf("rollback is not available for named processes")
+	}
+
+	build, ok := o.artifacts.Get(id)
+	if !ok {
+		return fmt.Errorf("build #%d not found", id)
+	}
+
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+
+	fmt.Printf("\n⏪ Rolling back to build #%d...\n", build.ID)
+	o.stopProcessLocked(o.app)
+	o.app.crashes = nil
+
+	if _, err := o.startProcessLocked(o.app, build, false); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Rolled back to build #%d\n", build.ID)
+	return nil
+}
+
+// Shutdown gracefully stops all running processes
 func (o *Optimizer) Shutdown() {
 	o.processMu.Lock()
 	defer o.processMu.Unlock()
 
-	if o.currentProcess != nil && o.currentProcess.Process != nil {
-		fmt.Printf("\nStopping process (PID: %d)...\n", o.currentProcess.Process.Pid)
-
-		// Try graceful shutdown
-		if err := o.currentProcess.Process.Signal(os.Interrupt); err == nil {
-			done := make(chan error)
-			go func() {
-				done <- o.currentProcess.Wait()
-			}()
-
-			select {
-			case <-done:
-				fmt.Println("Process stopped gracefully")
-			case <-time.After(2 * time.Second):
-				fmt.Println("Force killing process...")
-				o.currentProcess.Process.Kill()
-				o.currentProcess.Wait()
-			}
-		} else {
-			o.currentProcess.Process.Kill()
-			o.currentProcess.Wait()
-		}
+	hctx := hooks.Context{}
+	if o.app != nil && o.app.current != nil {
+		hctx.PID = o.app.current.Pid()
+	}
+	if err := o.hooks.Run(hooks.OnShutdown, hctx); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
 
-		o.currentProcess = nil
+	for _, svc := range o.services {
+		if svc.current != nil {
+			fmt.Println()
+			o.stopProcessLocked(svc)
+		}
 	}
+	o.procCtl.CloseListeners()
+	o.output.Close()
+
+	// Flush queued events so subscribers see everything before exit
+	o.bus.Close()
 }
@@ -0,0 +1,96 @@
+package optimizer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hotreloader/pkg/artifact"
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/hooks"
+	"hotreloader/pkg/pipeline"
+)
+
+// runPipeline runs the configured stages for a change, or for the initial
+// build when trigger is empty. The build and restart stages run the active
+// plugin and restart the application or the affected processes.
+func (o *Optimizer) runPipeline(trigger string, affectedFiles []string, hctx hooks.Context, rebuildStart time.Time) error {
+	var build *artifact.Build
+	builtins := map[string]pipeline.Builtin{
+		config.StageRestart: func() error {
+			return o.restartStage(trigger, affectedFiles, hctx, build)
+		},
+	}
+	if o.pluginMgr.GetActivePlugin() != nil {
+		builtins[config.StageBuild] = func() error {
+			b, err := o.build(trigger, affectedFiles, &hctx, rebuildStart)
+			if err != nil {
+				return err
+			}
+			build = b
+			return o.hooks.Run(hooks.PostBuild, hctx)
+		}
+	}
+
+	env := []string{
+		"HOTRELOADER_CHANGED_FILES=" + strings.Join(hctx.ChangedFiles, string(os.PathListSeparator)),
+		"HOTRELOADER_AFFECTED_COUNT=" + strconv.Itoa(len(affectedFiles)),
+	}
+
+	start := time.Now()
+	stages, err := o.pipeline.Run(trigger, env, builtins, func(result events.StageResult) {
+		o.bus.Publish(events.StageFinished{Meta: events.Now(), Trigger: trigger, Stage: result})
+	})
+	o.bus.Publish(events.PipelineFinished{
+		Meta:     events.Now(),
+		Trigger:  trigger,
+		Stages:   stages,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+
+	if err != nil {
+		return fmt.Errorf("pipeline failed: %w", err)
+	}
+	return nil
+}
+
+// restartStage restarts the application with the build of this pipeline
+// run, or with the running build when the build stage was skipped, using
+// the restart strategy of the change. Without an application it starts or
+// restarts the named processes.
+func (o *Optimizer) restartStage(trigger string, affectedFiles []string, hctx hooks.Context, build *artifact.Build) error {
+	if o.app == nil {
+		if trigger == "" {
+			return o.startServices()
+		}
+		o.restartAffected(hctx, affectedFiles)
+		return nil
+	}
+
+	if active := o.pluginMgr.GetActivePlugin(); active == nil || active.Name() != "go" {
+		return fmt.Errorf("no application to restart: only Go builds produce one")
+	}
+
+	if build == nil {
+		build = o.currentBuild()
+	} else if o.skipUnchanged(o.app, trigger, build) {
+		return nil
+	}
+
+	return o.restartService(o.app, hctx, build, o.strategyFor(trigger), trigger == "")
+}
+
+// currentBuild returns the build the application is running, if any
+func (o *Optimizer) currentBuild() *artifact.Build {
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+
+	if o.app.current == nil {
+		return nil
+	}
+	return o.app.current.build
+}
@@ -0,0 +1,131 @@
+package optimizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/pattern"
+	"hotreloader/pkg/process"
+)
+
+// ruleTimeout is used for rule commands that do not set their own timeout
+const ruleTimeout = 5 * time.Minute
+
+// rule handles changes to matching files with its own actions
+type rule struct {
+	name       string
+	patterns   pattern.List
+	command    string
+	timeout    time.Duration
+	signal     os.Signal
+	signalName string
+	reload     bool
+	build      bool
+}
+
+// newRules converts validated rule configs
+func newRules(cfgs []config.RuleConfig) []*rule {
+	rules := make([]*rule, 0, len(cfgs))
+	for i, c := range cfgs {
+		r := &rule{
+			name:     c.Name,
+			patterns: c.Patterns,
+			command:  c.Command,
+			timeout:  c.Timeout.Duration,
+			reload:   c.Reload,
+			build:    c.Build,
+		}
+		if r.name == "" {
+			r.name = fmt.Sprintf("#%d", i+1)
+		}
+		if r.timeout <= 0 {
+			r.timeout = ruleTimeout
+		}
+		if c.Signal != "" {
+			// Config validation already rejected unknown signals
+			r.signal, r.signalName, _ = process.ParseSignal(c.Signal)
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// matchRule returns the first rule matching a changed file
+func (o *Optimizer) matchRule(filePath string) *rule {
+	rel := pattern.Rel(o.projectDir, filePath)
+	for _, r := range o.rules {
+		if r.patterns.Match(rel) {
+			return r
+		}
+	}
+	return nil
+}
+
+// applyRule runs the actions of a rule for a changed file in order,
+// stopping at the first that fails
+func (o *Optimizer) applyRule(r *rule, filePath string) error {
+	fmt.Printf("\n📐 Rule %s matched %s\n", r.name, filePath)
+
+	if r.command != "" {
+		if err := o.runRuleCommand(r, filePath); err != nil {
+			return fmt.Errorf("rule %s: %w", r.name, err)
+		}
+	}
+
+	if r.signal != nil {
+		o.signalServices(r.signal, r.signalName, filePath)
+	}
+
+	if r.reload {
+		o.bus.Publish(events.ReloadRequested{Meta: events.Now(), Trigger: filePath})
+	}
+	return nil
+}
+
+// runRuleCommand runs a rule command in the project directory
+func (o *Optimizer) runRuleCommand(r *rule, filePath string) error {
+	fmt.Printf("▶️  Running %s\n", r.command)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.command)
+	process.KillGroupOnCancel(cmd)
+	cmd.Dir = o.projectDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "HOTRELOADER_CHANGED_FILES="+filePath)
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%q timed out after %v", r.command, r.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%q failed: %w", r.command, err)
+	}
+	return nil
+}
+
+// signalServices delivers a signal to the running application, or to the
+// named processes whose patterns match the changed file
+func (o *Optimizer) signalServices(sig os.Signal, name, filePath string) {
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+
+	sent := false
+	for _, svc := range o.services {
+		if svc.affectedBy(o.projectDir, []string{filePath}) && o.signalLocked(svc, sig, name) {
+			sent = true
+		}
+	}
+
+	if !sent {
+		fmt.Printf("⚠️  No running process to send %s to\n", name)
+	}
+}
@@ -0,0 +1,64 @@
+package optimizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunRuleCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		wantErr  string
+		wantFile string
+	}{
+		{
+			name:     "runs in the project with the changed file",
+			command:  `echo "$HOTRELOADER_CHANGED_FILES" > out`,
+			wantFile: "a.txt",
+		},
+		{
+			name:    "failure names the command",
+			command: "exit 3",
+			wantErr: `"exit 3" failed: exit status 3`,
+		},
+		{
+			name:    "timeout",
+			command: "sleep 30",
+			wantErr: `"sleep 30" timed out after 200ms`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			o := &Optimizer{projectDir: dir}
+			r := &rule{command: tt.command, timeout: 200 * time.Millisecond}
+
+			start := time.Now()
+			err := o.runRuleCommand(r, "a.txt")
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("runRuleCommand returned after %v", elapsed)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("runRuleCommand: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("runRuleCommand error = %v, want %q", err, tt.wantErr)
+			}
+
+			if tt.wantFile != "" {
+				data, err := os.ReadFile(filepath.Join(dir, "out"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.TrimSpace(string(data)); got != tt.wantFile {
+					t.Errorf("HOTRELOADER_CHANGED_FILES = %q, want %q", got, tt.wantFile)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/hooks"
+	"hotreloader/pkg/pattern"
+	"hotreloader/pkg/process"
+)
+
+// appService is the name of the service running the build output
+const appService = "app"
+
+// service is a long-running process managed by the optimizer. Without
+// configured processes there is a single "app" service running the build
+// output. Otherwise each process from the Procfile or config is a service
+// running its own command, restarted independently of the others.
+type service struct {
+	name      string
+	command   string
+	patterns  pattern.List
+	readiness []config.ProbeConfig
+	ctl       *process.Controller
+
+	// Guarded by Optimizer.processMu
+	current *appProcess
+	gen     int
+	crashes []time.Time
+}
+
+// newServices returns the app service, or the named services when
+// processes are configured, in which case the app service is nil
+func newServices(cfg *config.Config, appCtl, ctl *process.Controller) (*service, []*service) {
+	if len(cfg.Processes) == 0 {
+		app := &service{
+			name:      appService,
+			readiness: cfg.Readiness,
+			ctl:       appCtl,
+		}
+		return app, []*service{app}
+	}
+
+	services := make([]*service, 0, len(cfg.Processes))
+	for _, p := range cfg.Processes {
+		services = append(services, &service{
+			name:      p.Name,
+			command:   p.Command,
+			patterns:  p.Patterns,
+			readiness: p.Readiness,
+			ctl:       ctl,
+		})
+	}
+	return nil, services
+}
+
+// label names the service in console messages
+func (s *service) label() string {
+	if s.name == appService {
+		return "application"
+	}
+	return s.name
+}
+
+// affectedBy reports whether a change to any of files restarts the service
+func (s *service) affectedBy(projectDir string, files []string) bool {
+	if len(s.patterns) == 0 {
+		return true
+	}
+	for _, f := range files {
+		if s.patterns.Match(pattern.Rel(projectDir, f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceNames lists the names of services
+func serviceNames(services []*service) string {
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = s.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// startServices starts every named process
+func (o *Optimizer) startServices() error {
+	var failed []string
+	for _, svc := range o.services {
+		if err := o.restartService(svc, hooks.Context{}, nil, nil, true); err != nil {
+			failed = append(failed, svc.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to start %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// restartAffected restarts the named processes whose patterns match one of
+// the changed or affected files, leaving the others running. The restart
+// strategy of the changed file applies to each of them.
+func (o *Optimizer) restartAffected(hctx hooks.Context, files []string) {
+	s := o.strategyFor(firstFile(hctx.ChangedFiles))
+	for _, svc := range o.services {
+		if !svc.affectedBy(o.projectDir, files) {
+			continue
+		}
+
+		o.restartService(svc, hctx, nil, s, false)
+	}
+}
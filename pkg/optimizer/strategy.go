@@ -0,0 +1,127 @@
+package optimizer
+
+import (
+	"fmt"
+	"os"
+
+	"hotreloader/pkg/artifact"
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/hooks"
+	"hotreloader/pkg/pattern"
+	"hotreloader/pkg/process"
+)
+
+// strategy decides how a process takes up a change
+type strategy struct {
+	kind       string
+	patterns   pattern.List
+	signal     os.Signal
+	signalName string
+}
+
+// restarts reports whether the strategy replaces the running process
+func (s *strategy) restarts() bool {
+	return s.kind == config.StrategyRestart || s.kind == config.StrategyOverlap
+}
+
+// defaultStrategy restarts the process
+var defaultStrategy = &strategy{kind: config.StrategyRestart}
+
+// newStrategies converts validated strategy configs
+func newStrategies(cfgs []config.StrategyConfig) []*strategy {
+	strategies := make([]*strategy, 0, len(cfgs))
+	for _, c := range cfgs {
+		s := &strategy{kind: c.Strategy, patterns: c.Patterns}
+		if c.Strategy == config.StrategySignal {
+			// Config validation already rejected unknown signals
+			s.signal, s.signalName, _ = process.ParseSignal(c.Signal)
+		}
+		strategies = append(strategies, s)
+	}
+	return strategies
+}
+
+// strategyFor returns the strategy of the first entry matching the changed
+// file, or the default restart
+func (o *Optimizer) strategyFor(filePath string) *strategy {
+	if filePath == "" {
+		return defaultStrategy
+	}
+
+	rel := pattern.Rel(o.projectDir, filePath)
+	for _, s := range o.strategies {
+		if s.patterns.Match(rel) {
+			return s
+		}
+	}
+	return defaultStrategy
+}
+
+// applyStrategy lets a service take up a change: it is restarted, with or
+// without overlap, signalled, or left alone. Browsers are told to reload
+// when the process is not restarted, as no readiness event follows.
+func (o *Optimizer) applyStrategy(svc *service, hctx hooks.Context, build *artifact.Build, s *strategy) error {
+	switch s.kind {
+	case config.StrategyNone:
+		fmt.Printf("⏸️  Leaving %s running (restart strategy: none)\n", svc.label())
+	case config.StrategySignal:
+		o.processMu.Lock()
+		sent := o.signalLocked(svc, s.signal, s.signalName)
+		o.processMu.Unlock()
+		if !sent {
+			return fmt.Errorf("no running %s to send %s to", svc.label(), s.signalName)
+		}
+	default:
+		return o.restartProcess(svc, hctx, build, s.kind == config.StrategyOverlap)
+	}
+
+	o.bus.Publish(events.ReloadRequested{Meta: events.Now(), Trigger: firstFile(hctx.ChangedFiles)})
+	return nil
+}
+
+// restartService starts a service, or restarts it to take up a change
+// using a strategy, and reports the start or restart on the event bus.
+// Strategies that leave the process running are only reported if they
+// fail. Without a strategy the service is simply restarted.
+func (o *Optimizer) restartService(svc *service, hctx hooks.Context, build *artifact.Build, s *strategy, initial bool) error {
+	restarts := s == nil || s.restarts()
+	if restarts {
+		o.bus.Publish(events.RestartStarted{Meta: events.Now(), Name: svc.name, Initial: initial})
+	}
+
+	var err error
+	if s == nil {
+		err = o.restartProcess(svc, hctx, build, false)
+	} else {
+		err = o.applyStrategy(svc, hctx, build, s)
+	}
+
+	if restarts || err != nil {
+		o.bus.Publish(events.RestartFinished{Meta: events.Now(), Name: svc.name, Initial: initial, Err: err})
+	}
+	return err
+}
+
+// signalLocked delivers a signal to the running process of a service and
+// reports whether it was sent
+func (o *Optimizer) signalLocked(svc *service, sig os.Signal, name string) bool {
+	proc := svc.current
+	if proc == nil || proc.Exited() {
+		return false
+	}
+	if err := proc.Signal(sig); err != nil {
+		fmt.Printf("⚠️  Failed to send %s to %s: %v\n", name, svc.label(), err)
+		return false
+	}
+	fmt.Printf("📨 Sent %s to %s (PID %d)\n", name, svc.label(), proc.Pid())
+	return true
+}
+
+// firstFile returns the first of files, or an empty string
+func firstFile(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return files[0]
+}
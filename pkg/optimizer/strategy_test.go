@@ -0,0 +1,169 @@
+package optimizer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/hooks"
+)
+
+func TestStrategyFor(t *testing.T) {
+	dir := t.TempDir()
+	o := &Optimizer{projectDir: dir, strategies: newStrategies([]config.StrategyConfig{
+		{Strategy: config.StrategyNone, Patterns: []string{"*.md"}},
+		{Strategy: config.StrategySignal, Signal: "SIGHUP", Patterns: []string{"config/", "!config/*.go"}},
+		{Strategy: config.StrategyOverlap, Patterns: []string{"**/*.go"}},
+	})}
+
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{name: "no changed file restarts", file: "", want: config.StrategyRestart},
+		{name: "no matching pattern restarts", file: "web/site.css", want: config.StrategyRestart},
+		{name: "first match", file: "docs/README.md", want: config.StrategyNone},
+		{name: "directory pattern", file: "config/app.toml", want: config.StrategySignal},
+		{name: "excluded file falls through", file: "config/load.go", want: config.StrategyOverlap},
+		{name: "file outside the project restarts", file: "/elsewhere/notes.txt", want: config.StrategyRestart},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			if file != "" && !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			if got := o.strategyFor(file); got.kind != tt.want {
+				t.Errorf("strategy = %s, want %s", got.kind, tt.want)
+			}
+		})
+	}
+
+	if s := o.strategyFor(filepath.Join(dir, "config/app.toml")); s.signalName != "SIGHUP" || s.signal == nil {
+		t.Errorf("signal strategy sends %q", s.signalName)
+	}
+}
+
+// hupScript reports each SIGHUP it receives
+const hupScript = "trap 'echo hup' HUP\necho ready\nwhile :; do sleep 0.05; done\n"
+
+func TestApplyStrategy(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       string
+		notRunning bool
+		wantErr    string
+		wantNewPID bool
+		wantEvents []string
+		wantOutput string
+	}{
+		{
+			name:       "restart stops before starting",
+			kind:       config.StrategyRestart,
+			wantNewPID: true,
+			wantEvents: []string{"stopping", "started", "ready"},
+		},
+		{
+			name:       "overlap starts before stopping",
+			kind:       config.StrategyOverlap,
+			wantNewPID: true,
+			wantEvents: []string{"started", "ready", "stopping"},
+		},
+		{
+			name:       "signal keeps the process",
+			kind:       config.StrategySignal,
+			wantEvents: []string{"reload"},
+			wantOutput: "hup",
+		},
+		{
+			name:       "none keeps the process",
+			kind:       config.StrategyNone,
+			wantEvents: []string{"reload"},
+		},
+		{
+			name:       "signal without a running process",
+			kind:       config.StrategySignal,
+			notRunning: true,
+			wantErr:    "no running application to send SIGHUP to",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, store := newTestOptimizer(t, false)
+			build := commitScript(t, store, hupScript)
+
+			oldPID := 0
+			if !tt.notRunning {
+				if err := o.restartProcess(o.app, hooks.Context{}, build, false); err != nil {
+					t.Fatalf("start: %v", err)
+				}
+				oldPID = o.app.current.Pid()
+			}
+
+			var mu sync.Mutex
+			var got []string
+			o.bus.Subscribe("test", events.SubscriberFunc(func(e events.Event) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch e.(type) {
+				case events.ProcessStarted:
+					got = append(got, "started")
+				case events.ProcessReady:
+					got = append(got, "ready")
+				case events.ProcessStopping:
+					got = append(got, "stopping")
+				case events.ReloadRequested:
+					got = append(got, "reload")
+				}
+			}), 100)
+
+			s := newStrategies([]config.StrategyConfig{{Strategy: tt.kind, Signal: "SIGHUP", Patterns: []string{"*"}}})[0]
+			err := o.applyStrategy(o.app, hooks.Context{ChangedFiles: []string{"a.txt"}}, build, s)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("applyStrategy: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("applyStrategy error = %v, want %q", err, tt.wantErr)
+			}
+
+			if tt.wantOutput != "" {
+				deadline := time.Now().Add(2 * time.Second)
+				for !hasLine(o, tt.wantOutput) && time.Now().Before(deadline) {
+					time.Sleep(20 * time.Millisecond)
+				}
+				if !hasLine(o, tt.wantOutput) {
+					t.Errorf("process did not print %q", tt.wantOutput)
+				}
+			}
+
+			if !tt.notRunning {
+				if newPID := o.app.current.Pid() != oldPID; newPID != tt.wantNewPID {
+					t.Errorf("process replaced = %v, want %v", newPID, tt.wantNewPID)
+				}
+			}
+
+			o.bus.Close()
+			if !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+// hasLine reports whether the application printed a line
+func hasLine(o *Optimizer, text string) bool {
+	for _, line := range o.output.Lines(appService, 20) {
+		if line.Text == text {
+			return true
+		}
+	}
+	return false
+}
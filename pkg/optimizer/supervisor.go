@@ -0,0 +1,224 @@
+package optimizer
+
+import (
+	"fmt"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/process"
+)
+
+// Restart policies applied when the application exits on its own
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// restartPolicy decides whether and when an exited process is restarted
+type restartPolicy struct {
+	mode        string
+	maxFailures int
+	window      time.Duration
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// newRestartPolicy fills in defaults for unset restart settings
+func newRestartPolicy(cfg config.RestartConfig) restartPolicy {
+	p := restartPolicy{
+		mode:        cfg.Policy,
+		maxFailures: cfg.MaxFailures,
+		window:      cfg.Window.Duration,
+		backoff:     cfg.Backoff.Duration,
+		maxBackoff:  cfg.MaxBackoff.Duration,
+	}
+
+	if p.mode == "" {
+		p.mode = RestartNever
+	}
+	if p.maxFailures <= 0 {
+		p.maxFailures = 5
+	}
+	if p.window <= 0 {
+		p.window = time.Minute
+	}
+	if p.backoff <= 0 {
+		p.backoff = 500 * time.Millisecond
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = 30 * time.Second
+	}
+
+	return p
+}
+
+// shouldRestart reports whether an exit with the given status is restarted
+func (p restartPolicy) shouldRestart(failed bool) bool {
+	switch p.mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return failed
+	default:
+		return false
+	}
+}
+
+// delay returns the exponential backoff before the n-th restart in the window
+func (p restartPolicy) delay(n int) time.Duration {
+	d := p.backoff
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+	return d
+}
+
+// crashReportLines is the number of output lines included with an unexpected exit
+const crashReportLines = 20
+
+// publishExit reports a finished process on the event bus. Unexpected exits
+// carry the last lines of output for the crash report.
+func (o *Optimizer) publishExit(proc *appProcess, unexpected bool) {
+	proc.stdout.Flush()
+	proc.stderr.Flush()
+
+	var tail []string
+	if unexpected {
+		for _, line := range o.output.Lines(proc.svc.name, crashReportLines) {
+			tail = append(tail, line.Text)
+		}
+	}
+
+	code, signal := proc.ExitStatus()
+	o.bus.Publish(events.ProcessExited{
+		Meta:       events.Now(),
+		Name:       proc.svc.name,
+		PID:        proc.Pid(),
+		ExitCode:   code,
+		Signal:     signal,
+		Unexpected: unexpected,
+		Err:        proc.Err(),
+		Output:     tail,
+	})
+}
+
+// exitDescription describes how an exited process ended
+func exitDescription(p *process.Process) string {
+	code, signal := p.ExitStatus()
+	if signal != "" {
+		return "signal: " + signal
+	}
+	return fmt.Sprintf("exit code %d", code)
+}
+
+// supervise watches a started process until it exits. A build whose process
+// passes its readiness probes and then survives the grace period is marked
+// good; one that never becomes ready is not. If the process exits on its
+// own, the exit is reported and the build is either rolled back (exit
+// within the grace period) or restarted according to the restart policy.
+func (o *Optimizer) supervise(proc *appProcess) {
+	// The grace period starts once the probes pass
+	var ready <-chan struct{}
+	if proc.build != nil {
+		ready = proc.ready
+	}
+	var grace <-chan time.Time
+
+	withinGrace := ready != nil
+	for exited := false; !exited; {
+		select {
+		case <-ready:
+			timer := time.NewTimer(o.gracePeriod)
+			defer timer.Stop()
+			grace = timer.C
+			ready = nil
+		case <-grace:
+			o.artifacts.MarkGood(proc.build.ID)
+			grace = nil
+			withinGrace = false
+		case <-proc.Done():
+			exited = true
+		}
+	}
+
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+
+	// A process stopped by the reloader is no longer current
+	svc := proc.svc
+	if svc.current != proc {
+		return
+	}
+	svc.current = nil
+	svc.ctl.Reap(proc.Process)
+	o.publishExit(proc, true)
+
+	if withinGrace && proc.checkGrace && o.autoRollback {
+		fmt.Printf("💥 Process %d exited within %v of starting\n", proc.Pid(), o.gracePeriod)
+		err := o.rollbackLocked(svc, proc.build)
+		if err == nil {
+			return
+		}
+		fmt.Printf("⚠️  Rollback failed: %v\n", err)
+	}
+
+	o.scheduleRestartLocked(proc)
+}
+
+// scheduleRestartLocked applies the restart policy to an exited process,
+// backing off exponentially and giving up on a crash loop
+func (o *Optimizer) scheduleRestartLocked(proc *appProcess) {
+	code, signal := proc.ExitStatus()
+	failed := code != 0 || signal != ""
+	if !o.restart.shouldRestart(failed) {
+		return
+	}
+
+	// Count the exits inside the crash-loop window
+	svc := proc.svc
+	now := time.Now()
+	recent := svc.crashes[:0]
+	for _, t := range svc.crashes {
+		if now.Sub(t) < o.restart.window {
+			recent = append(recent, t)
+		}
+	}
+	svc.crashes = append(recent, now)
+
+	if len(svc.crashes) >= o.restart.maxFailures {
+		fmt.Printf("🛑 Crash loop detected: %s exited %d times within %v. Not restarting until the next change.\n",
+			svc.label(), len(svc.crashes), o.restart.window)
+		o.bus.Publish(events.CrashLoop{
+			Meta:     events.Now(),
+			Name:     svc.name,
+			PID:      proc.Pid(),
+			Failures: len(svc.crashes),
+			Window:   o.restart.window,
+		})
+		return
+	}
+
+	delay := o.restart.delay(len(svc.crashes))
+	gen := svc.gen
+	fmt.Printf("🔁 Restarting %s in %v (policy: %s)...\n", svc.label(), delay, o.restart.mode)
+
+	go func() {
+		time.Sleep(delay)
+
+		o.processMu.Lock()
+		defer o.processMu.Unlock()
+
+		// A rebuild, rollback or shutdown happened while backing off
+		if svc.gen != gen || svc.current != nil {
+			return
+		}
+		if _, err := o.startProcessLocked(svc, proc.build, false); err != nil {
+			fmt.Printf("⚠️  Failed to restart process: %v\n", err)
+		}
+	}()
+}
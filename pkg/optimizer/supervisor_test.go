@@ -0,0 +1,286 @@
+package optimizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/artifact"
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/hooks"
+	"hotreloader/pkg/output"
+	"hotreloader/pkg/process"
+)
+
+const testGracePeriod = 300 * time.Millisecond
+
+// Scripts standing in for built binaries
+const (
+	readyScript  = "echo ready\nexec sleep 30\n"
+	silentScript = "exec sleep 30\n"
+	crashScript  = "echo ready\nsleep 0.1\nexit 1\n"
+	failScript   = "exit 3\n"
+)
+
+// newTestOptimizer returns an optimizer supervising the app service with a
+// log readiness probe, and the artifact store holding its builds
+func newTestOptimizer(t *testing.T, autoRollback bool) (*Optimizer, *artifact.Store) {
+	t.Helper()
+	dir := t.TempDir()
+
+	store, err := artifact.NewStore(filepath.Join(dir, "builds"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctl := process.NewController(process.MustParseStopSequence("SIGKILL"))
+	o := &Optimizer{
+		bus:          events.NewBus(),
+		hooks:        hooks.NewRunner(dir, config.HooksConfig{}),
+		artifacts:    store,
+		gracePeriod:  testGracePeriod,
+		autoRollback: autoRollback,
+		restart:      newRestartPolicy(config.RestartConfig{}),
+		output:       output.NewMux(config.OutputConfig{}, filepath.Join(dir, "logs")),
+		projectDir:   dir,
+	}
+	o.app = &service{
+		name: appService,
+		readiness: []config.ProbeConfig{{
+			Type:    "log",
+			Target:  "^ready$",
+			Timeout: config.Duration{Duration: 500 * time.Millisecond},
+		}},
+		ctl: ctl,
+	}
+
+	t.Cleanup(func() {
+		o.processMu.Lock()
+		defer o.processMu.Unlock()
+		o.stopProcessLocked(o.app)
+	})
+	return o, store
+}
+
+// commitScript stores a shell script as a build artifact
+func commitScript(t *testing.T, store *artifact.Store, script string) *artifact.Build {
+	t.Helper()
+	id, path := store.Reserve()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return store.Commit(id, path, "test")
+}
+
+// currentBuild returns the ID of the build the app service runs, or 0
+func currentBuild(o *Optimizer) int {
+	o.processMu.Lock()
+	defer o.processMu.Unlock()
+	if o.app.current == nil || o.app.current.build == nil {
+		return 0
+	}
+	return o.app.current.build.ID
+}
+
+// isGood reports whether the store marked a build good
+func isGood(store *artifact.Store, id int) bool {
+	b, ok := store.Get(id)
+	return ok && b.Good
+}
+
+func TestSuperviseMarksGoodAfterReadiness(t *testing.T) {
+	o, store := newTestOptimizer(t, false)
+	build := commitScript(t, store, readyScript)
+
+	if err := o.restartProcess(o.app, hooks.Context{}, build, false); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if isGood(store, build.ID) {
+		t.Fatal("build marked good before the grace period ended")
+	}
+
+	time.Sleep(testGracePeriod + 200*time.Millisecond)
+	if !isGood(store, build.ID) {
+		t.Error("build not marked good after the grace period")
+	}
+}
+
+func TestSuperviseRollback(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		autoRollback bool
+		wantErr      string
+		wantCurrent  func(good, bad int) int
+	}{
+		{
+			name:         "probe failure rolls back",
+			script:       silentScript,
+			autoRollback: true,
+			wantErr:      "readiness probe log /^ready$/ failed",
+			wantCurrent:  func(good, bad int) int { return good },
+		},
+		{
+			name:         "exit before ready rolls back",
+			script:       failScript,
+			autoRollback: true,
+			wantErr:      "process exited before becoming ready (exit code 3)",
+			wantCurrent:  func(good, bad int) int { return good },
+		},
+		{
+			name:         "exit within grace rolls back",
+			script:       crashScript,
+			autoRollback: true,
+			wantCurrent:  func(good, bad int) int { return good },
+		},
+		{
+			name:        "probe failure without rollback keeps the build",
+			script:      silentScript,
+			wantErr:     "readiness probe log /^ready$/ failed",
+			wantCurrent: func(good, bad int) int { return bad },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, store := newTestOptimizer(t, tt.autoRollback)
+
+			good := commitScript(t, store, readyScript)
+			store.MarkGood(good.ID)
+			bad := commitScript(t, store, tt.script)
+
+			err := o.restartProcess(o.app, hooks.Context{}, bad, false)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("restart: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("restart error = %v, want %q", err, tt.wantErr)
+			}
+
+			// Wait past the grace period for the supervisor to act
+			want := tt.wantCurrent(good.ID, bad.ID)
+			deadline := time.Now().Add(3 * time.Second)
+			for currentBuild(o) != want && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			time.Sleep(testGracePeriod + 200*time.Millisecond)
+
+			if got := currentBuild(o); got != want {
+				t.Errorf("current build = #%d, want #%d", got, want)
+			}
+			if isGood(store, bad.ID) {
+				t.Errorf("build #%d marked good although it failed", bad.ID)
+			}
+		})
+	}
+}
+
+func TestRestartPolicyDelay(t *testing.T) {
+	p := newRestartPolicy(config.RestartConfig{
+		Backoff:    config.Duration{Duration: 100 * time.Millisecond},
+		MaxBackoff: config.Duration{Duration: time.Second},
+	})
+
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{n: 1, want: 100 * time.Millisecond},
+		{n: 2, want: 200 * time.Millisecond},
+		{n: 3, want: 400 * time.Millisecond},
+		{n: 4, want: 800 * time.Millisecond},
+		{n: 5, want: time.Second},
+		{n: 50, want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.n); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	tests := []struct {
+		policy      string
+		wantFailed  bool
+		wantSuccess bool
+	}{
+		{policy: ""},
+		{policy: RestartNever},
+		{policy: RestartOnFailure, wantFailed: true},
+		{policy: RestartAlways, wantFailed: true, wantSuccess: true},
+	}
+	for _, tt := range tests {
+		p := newRestartPolicy(config.RestartConfig{Policy: tt.policy})
+		if got := p.shouldRestart(true); got != tt.wantFailed {
+			t.Errorf("%q: shouldRestart(failed) = %v, want %v", tt.policy, got, tt.wantFailed)
+		}
+		if got := p.shouldRestart(false); got != tt.wantSuccess {
+			t.Errorf("%q: shouldRestart(succeeded) = %v, want %v", tt.policy, got, tt.wantSuccess)
+		}
+	}
+}
+
+func TestCrashLoop(t *testing.T) {
+	tests := []struct {
+		name          string
+		window        time.Duration
+		wantCrashLoop bool
+	}{
+		{name: "exits within the window stop restarts", window: time.Minute, wantCrashLoop: true},
+		// Each exit of crashScript comes more than a window after the last
+		{name: "exits spread out keep restarting", window: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, store := newTestOptimizer(t, false)
+			o.restart = newRestartPolicy(config.RestartConfig{
+				Policy:      RestartOnFailure,
+				MaxFailures: 3,
+				Window:      config.Duration{Duration: tt.window},
+				Backoff:     config.Duration{Duration: 10 * time.Millisecond},
+			})
+
+			received := make(chan events.Event, 100)
+			o.bus.Subscribe("test", events.SubscriberFunc(func(e events.Event) { received <- e }), 100)
+
+			build := commitScript(t, store, crashScript)
+			if err := o.restartProcess(o.app, hooks.Context{}, build, false); err != nil {
+				t.Fatalf("restart: %v", err)
+			}
+
+			starts, crashLoop := 0, false
+			timeout := time.After(1500 * time.Millisecond)
+			for done := false; !done; {
+				select {
+				case e := <-received:
+					switch e := e.(type) {
+					case events.ProcessStarted:
+						starts++
+					case events.CrashLoop:
+						crashLoop = true
+						if e.Failures != 3 || e.Window != tt.window {
+							t.Errorf("crash loop after %d failures in %v, want 3 in %v", e.Failures, e.Window, tt.window)
+						}
+					}
+				case <-timeout:
+					done = true
+				}
+			}
+
+			if crashLoop != tt.wantCrashLoop {
+				t.Fatalf("crash loop reported = %v, want %v", crashLoop, tt.wantCrashLoop)
+			}
+			if tt.wantCrashLoop && starts != 3 {
+				t.Errorf("process started %d times, want 3 before giving up", starts)
+			}
+			if !tt.wantCrashLoop && starts <= 3 {
+				t.Errorf("process started %d times, want restarts past the failure limit", starts)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package optimizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/pattern"
+	"hotreloader/pkg/testrunner"
+)
+
+// coverage selects tests by the files they cover. Coverage of packages is
+// collected in the background, one package at a time, and persisted in the
+// cache directory.
+type coverage struct {
+	m       *testrunner.CoverageMap
+	mu      sync.Mutex
+	graph   *analyzer.GoGraph
+	pending map[string]bool
+	running bool
+}
+
+// newCoverage loads the persisted coverage map of a project
+func newCoverage(projectDir string) *coverage {
+	path := filepath.Join(config.CacheDir(projectDir), testrunner.CoverageFileName)
+	m, err := testrunner.LoadCoverageMap(path)
+	if err != nil {
+		fmt.Printf("⚠️  %v, collecting coverage again\n", err)
+		m = testrunner.NewCoverageMap(path)
+	}
+	return &coverage{m: m, pending: make(map[string]bool)}
+}
+
+// loadTestGraph lists the packages of the module for the test mode
+func (o *Optimizer) loadTestGraph() error {
+	graph, err := o.goGraphFor("")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧪 Test mode: %d packages, %d with tests\n", graph.Len(), len(graph.Tested()))
+
+	if o.coverage != nil {
+		var missing []string
+		for _, pkg := range graph.Tested() {
+			if !o.coverage.m.Has(pkg) {
+				missing = append(missing, pkg)
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Printf("📊 Collecting coverage for %d package(s) in the background\n", len(missing))
+			o.refreshCoverage(graph, missing)
+		}
+	}
+	return nil
+}
+
+// runAffectedTests runs the tests of the package containing a changed file
+// and of every package that imports it, directly or transitively
+func (o *Optimizer) runAffectedTests(filePath string) error {
+	// Editors and tools write short-lived temporary files
+	if _, err := os.Stat(filePath); err != nil {
+		return nil
+	}
+
+	graph, err := o.goGraphFor(filePath)
+	if err != nil {
+		o.bus.Publish(events.TestRunFinished{Meta: events.Now(), Trigger: filePath, Err: err})
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	var packages []string
+	switch {
+	case filepath.Ext(filePath) == ".go", filepath.Base(filePath) == "go.mod", filepath.Base(filePath) == "go.sum":
+		packages = graph.Impact(filePath).Tests
+	default:
+		// Other files, such as testdata, affect the package containing them
+		pkg, ok := graph.PackageContaining(filePath)
+		if !ok {
+			return nil
+		}
+		packages = graph.Affected(pkg.ImportPath).Tests
+	}
+
+	if len(packages) == 0 {
+		fmt.Printf("🧪 No tests affected by %s\n", filePath)
+		return nil
+	}
+
+	// The change may alter what the tests execute
+	if o.coverage != nil {
+		defer o.refreshCoverage(graph, packages)
+	}
+
+	selected, ok := o.selectByCoverage(filePath, packages)
+	if ok && len(selected) == 0 {
+		fmt.Printf("🧪 No tests cover %s\n", filePath)
+		return nil
+	}
+
+	run := func() (*testrunner.Result, error) { return o.tests.Run(packages) }
+	if ok {
+		// A new slice, as the deferred refresh holds the affected packages
+		selectedPkgs := make([]string, 0, len(selected))
+		count := 0
+		for pkg, tests := range selected {
+			selectedPkgs = append(selectedPkgs, pkg)
+			count += len(tests)
+		}
+		sort.Strings(selectedPkgs)
+		packages = selectedPkgs
+		fmt.Printf("📊 Coverage selects %d test(s) in %d package(s)\n", count, len(packages))
+		run = func() (*testrunner.Result, error) { return o.tests.RunSelected(selected) }
+	}
+
+	o.bus.Publish(events.TestRunStarted{
+		Meta:     events.Now(),
+		Trigger:  filePath,
+		Packages: packages,
+	})
+
+	result, err := run()
+	if err != nil {
+		o.bus.Publish(events.TestRunFinished{
+			Meta:     events.Now(),
+			Trigger:  filePath,
+			Packages: packages,
+			Err:      err,
+		})
+		return fmt.Errorf("test run failed: %w", err)
+	}
+
+	o.bus.Publish(events.TestRunFinished{
+		Meta:     events.Now(),
+		Trigger:  filePath,
+		Packages: packages,
+		Passed:   result.Passed,
+		Failed:   result.Failed,
+		Skipped:  result.Skipped,
+		Failures: result.Failures,
+		Duration: result.Duration,
+	})
+	return nil
+}
+
+// selectByCoverage returns the tests of the affected packages that execute
+// a changed source file. It reports false when coverage cannot tell, such
+// as for test files, non-Go files and packages not collected yet.
+func (o *Optimizer) selectByCoverage(filePath string, packages []string) (map[string][]string, bool) {
+	if o.coverage == nil || filepath.Ext(filePath) != ".go" || strings.HasSuffix(filePath, "_test.go") {
+		return nil, false
+	}
+	return o.coverage.m.Tests(pattern.Rel(o.projectDir, filePath), packages)
+}
+
+// refreshCoverage queues packages for coverage collection, starting the
+// background collector if it is idle
+func (o *Optimizer) refreshCoverage(graph *analyzer.GoGraph, packages []string) {
+	c := o.coverage
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.graph = graph
+	for _, pkg := range packages {
+		c.pending[pkg] = true
+	}
+	if !c.running {
+		c.running = true
+		go o.collectCoverage()
+	}
+}
+
+// collectCoverage collects coverage of pending packages until none is left,
+// saving the map after each package
+func (o *Optimizer) collectCoverage() {
+	c := o.coverage
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.running = false
+			c.mu.Unlock()
+			return
+		}
+		pending := make([]string, 0, len(c.pending))
+		for pkg := range c.pending {
+			pending = append(pending, pkg)
+		}
+		sort.Strings(pending)
+		pkg, graph := pending[0], c.graph
+		delete(c.pending, pkg)
+		c.mu.Unlock()
+
+		tests, files, err := o.tests.CollectCoverage(graph, pkg)
+		if err != nil {
+			fmt.Printf("⚠️  Coverage of %s not collected: %v\n", pkg, err)
+			continue
+		}
+		c.m.Update(pkg, tests, files)
+		if err := c.m.Save(); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
+}
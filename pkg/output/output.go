@@ -0,0 +1,282 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/config"
+)
+
+const (
+	// DefaultBufferLines is the number of output lines kept in memory
+	DefaultBufferLines = 500
+	// maxLineLength is the longest line kept whole; longer output without
+	// a line ending is emitted in pieces
+	maxLineLength = 64 << 10
+)
+
+// Stream names the output stream a line was written to
+type Stream string
+
+const (
+	Stdout Stream = "stdout"
+	Stderr Stream = "stderr"
+)
+
+// colors are assigned to process names in order of first use
+var colors = []string{"36", "35", "33", "32", "34", "91", "96", "95"}
+
+// Line is a single line of process output
+type Line struct {
+	Time   time.Time
+	Name   string
+	Stream Stream
+	Text   string
+}
+
+// String returns the line with its process prefix
+func (l Line) String() string {
+	return fmt.Sprintf("[%s] %s", l.Name, l.Text)
+}
+
+// Mux collects the output of child processes. Every line is printed with a
+// colored prefix naming its process, kept in a ring buffer and optionally
+// appended to a rotating log file per process.
+type Mux struct {
+	mu         sync.Mutex
+	stdout     io.Writer
+	stderr     io.Writer
+	color      bool
+	timestamps bool
+	ring       []Line
+	next       int
+	full       bool
+	colors     map[string]string
+	logDir     string
+	logSize    int64
+	logFiles   int
+	logs       map[string]*RotatingFile
+}
+
+// NewMux creates an output multiplexer. logDir is where log files are
+// written when logging is enabled in cfg.
+func NewMux(cfg config.OutputConfig, logDir string) *Mux {
+	size := cfg.BufferLines
+	if size <= 0 {
+		size = DefaultBufferLines
+	}
+
+	m := &Mux{
+		stdout:     os.Stdout,
+		stderr:     os.Stderr,
+		color:      !cfg.NoColor && colorSupported(),
+		timestamps: cfg.Timestamps,
+		ring:       make([]Line, size),
+		colors:     make(map[string]string),
+		logs:       make(map[string]*RotatingFile),
+	}
+
+	if cfg.Logs.Enabled {
+		m.logDir = logDir
+		m.logSize = int64(cfg.Logs.MaxSizeMB) << 20
+		m.logFiles = cfg.Logs.MaxFiles
+	}
+
+	return m
+}
+
+// Writers returns fresh writers for the stdout and stderr of a process.
+// Call Flush on both once the process has exited to emit a trailing
+// line without a newline.
+func (m *Mux) Writers(name string) (*Writer, *Writer) {
+	return &Writer{mux: m, name: name, stream: Stdout},
+		&Writer{mux: m, name: name, stream: Stderr}
+}
+
+// Lines returns up to n of the most recent lines of the named process,
+// oldest first. An empty name returns lines of all processes.
+func (m *Mux) Lines(name string, n int) []Line {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := m.next
+	if m.full {
+		count = len(m.ring)
+	}
+
+	var lines []Line
+	for i := 0; i < count && len(lines) < n; i++ {
+		idx := (m.next - 1 - i + len(m.ring)) % len(m.ring)
+		if name == "" || m.ring[idx].Name == name {
+			lines = append(lines, m.ring[idx])
+		}
+	}
+
+	// Collected newest first
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// Tail returns the most recent lines of all processes as text
+func (m *Mux) Tail(n int) []string {
+	lines := m.Lines("", n)
+	text := make([]string, len(lines))
+	for i, l := range lines {
+		text[i] = l.String()
+	}
+	return text
+}
+
+// Close closes the log files
+func (m *Mux) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.logs {
+		if f != nil {
+			f.Close()
+		}
+	}
+	m.logs = make(map[string]*RotatingFile)
+}
+
+// emit prints, buffers and logs one complete line
+func (m *Mux) emit(line Line) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ring[m.next] = line
+	m.next = (m.next + 1) % len(m.ring)
+	if m.next == 0 {
+		m.full = true
+	}
+
+	out := m.stdout
+	if line.Stream == Stderr {
+		out = m.stderr
+	}
+	fmt.Fprintln(out, m.prefix(line)+line.Text)
+
+	if m.logDir != "" {
+		m.writeLog(line)
+	}
+}
+
+// prefix formats the process name and optional timestamp of a line
+func (m *Mux) prefix(line Line) string {
+	prefix := "[" + line.Name + "]"
+	if m.color {
+		c, ok := m.colors[line.Name]
+		if !ok {
+			c = colors[len(m.colors)%len(colors)]
+			m.colors[line.Name] = c
+		}
+		prefix = "\x1b[" + c + "m" + prefix + "\x1b[0m"
+	}
+	if m.timestamps {
+		prefix += " " + line.Time.Format("15:04:05.000")
+	}
+	return prefix + " "
+}
+
+// writeLog appends a line to the log file of its process. A log file that
+// cannot be opened is not tried again; other processes keep their logs.
+func (m *Mux) writeLog(line Line) {
+	f, ok := m.logs[line.Name]
+	if !ok {
+		var err error
+		f, err = OpenRotatingFile(m.logDir, line.Name+".log", m.logSize, m.logFiles)
+		if err != nil {
+			fmt.Fprintf(m.stderr, "Warning: cannot write %s log: %v\n", line.Name, err)
+		}
+		m.logs[line.Name] = f
+	}
+	if f == nil {
+		return
+	}
+
+	fmt.Fprintf(f, "%s [%s] %s\n", line.Time.Format("2006-01-02T15:04:05.000"), line.Stream, line.Text)
+}
+
+// Writer splits the output of one process stream into lines for the mux
+type Writer struct {
+	mux     *Mux
+	name    string
+	stream  Stream
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Write implements io.Writer. Lines end in \n, \r\n or a lone \r, which
+// progress bars use to redraw a line.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		end := i + 1
+		if w.partial[i] == '\r' {
+			if end == len(w.partial) {
+				// The \n of a \r\n may follow in the next write
+				break
+			}
+			if w.partial[end] == '\n' {
+				end++
+			}
+		}
+		w.emit(w.partial[:i])
+		w.partial = w.partial[end:]
+	}
+
+	// Output that never ends a line is not buffered without bound
+	for len(w.partial) > maxLineLength {
+		w.emit(w.partial[:maxLineLength])
+		w.partial = w.partial[maxLineLength:]
+	}
+
+	return len(p), nil
+}
+
+// Flush emits a trailing line that did not end in a newline
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if text := bytes.TrimSuffix(w.partial, []byte("\r")); len(text) > 0 {
+		w.emit(text)
+	}
+	w.partial = nil
+}
+
+// emit sends one line without its line ending to the mux
+func (w *Writer) emit(text []byte) {
+	w.mux.emit(Line{
+		Time:   time.Now(),
+		Name:   w.name,
+		Stream: w.stream,
+		Text:   string(text),
+	})
+}
+
+// colorSupported reports whether stdout is a terminal that accepts colors
+func colorSupported() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,203 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hotreloader/pkg/config"
+)
+
+// newTestMux returns a mux writing to buffers instead of the terminal
+func newTestMux(cfg config.OutputConfig, logDir string) (*Mux, *bytes.Buffer) {
+	m := NewMux(cfg, logDir)
+	var out bytes.Buffer
+	m.stdout, m.stderr, m.color = &out, &out, false
+	return m, &out
+}
+
+// texts returns the text of lines
+func texts(lines []Line) []string {
+	var result []string
+	for _, l := range lines {
+		result = append(result, l.Text)
+	}
+	return result
+}
+
+func TestMuxLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		emitted int
+		process string
+		n       int
+		want    []string
+	}{
+		{name: "not full", emitted: 2, n: 10, want: []string{"a0", "b1"}},
+		{name: "wrapped", emitted: 6, n: 10, want: []string{"a2", "b3", "a4", "b5"}},
+		{name: "most recent", emitted: 6, n: 2, want: []string{"a4", "b5"}},
+		{name: "one process", emitted: 6, process: "a", n: 10, want: []string{"a2", "a4"}},
+		{name: "empty", n: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestMux(config.OutputConfig{BufferLines: 4}, "")
+			for i := 0; i < tt.emitted; i++ {
+				name := []string{"a", "b"}[i%2]
+				m.emit(Line{Name: name, Stream: Stdout, Text: fmt.Sprintf("%s%d", name, i)})
+			}
+			if got := texts(m.Lines(tt.process, tt.n)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterLines(t *testing.T) {
+	long := strings.Repeat("x", maxLineLength)
+
+	tests := []struct {
+		name      string
+		writes    []string
+		want      []string
+		wantFlush []string
+	}{
+		{name: "lines", writes: []string{"a\nb\n"}, want: []string{"a", "b"}},
+		{name: "split line", writes: []string{"ab", "c\nd"}, want: []string{"abc"}, wantFlush: []string{"d"}},
+		{name: "crlf", writes: []string{"a\r\nb\r\n"}, want: []string{"a", "b"}},
+		{name: "crlf across writes", writes: []string{"a\r", "\nb\n"}, want: []string{"a", "b"}},
+		{name: "progress", writes: []string{"10%\r20%\r", "30%\r"}, want: []string{"10%", "20%"}, wantFlush: []string{"30%"}},
+		{name: "empty lines", writes: []string{"\n\r\n"}, want: []string{"", ""}},
+		{name: "long output without line end", writes: []string{long, long + "y"}, want: []string{long, long}, wantFlush: []string{"y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestMux(config.OutputConfig{}, "")
+			stdout, _ := m.Writers("app")
+			for _, w := range tt.writes {
+				stdout.Write([]byte(w))
+			}
+			if got := texts(m.Lines("", 100)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+
+			stdout.Flush()
+			want := append(append([]string(nil), tt.want...), tt.wantFlush...)
+			if got := texts(m.Lines("", 100)); !reflect.DeepEqual(got, want) {
+				t.Errorf("lines after Flush = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMuxLogFailureKeepsOtherLogs(t *testing.T) {
+	dir := t.TempDir()
+	// A directory in the way of one process's log file
+	if err := os.Mkdir(filepath.Join(dir, "broken.log"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.OutputConfig{}
+	cfg.Logs.Enabled = true
+	m, out := newTestMux(cfg, dir)
+	defer m.Close()
+
+	for i := 0; i < 2; i++ {
+		m.emit(Line{Name: "broken", Stream: Stdout, Text: "lost"})
+		m.emit(Line{Name: "web", Stream: Stderr, Text: "kept"})
+	}
+
+	if n := strings.Count(out.String(), "cannot write broken log"); n != 1 {
+		t.Errorf("warned %d times, want once:\n%s", n, out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "web.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "[stderr] kept\n"); n != 2 {
+		t.Errorf("web.log has %d lines, want 2:\n%s", n, data)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		maxFiles int
+		want     map[string]string
+	}{
+		{
+			name:     "fits",
+			writes:   []string{"aaaa\n", "bbbb\n"},
+			maxFiles: 2,
+			want:     map[string]string{"app.log": "aaaa\nbbbb\n"},
+		},
+		{
+			name:     "rotates",
+			writes:   []string{"aaaa\n", "bbbb\n", "cccc\n"},
+			maxFiles: 2,
+			want:     map[string]string{"app.log": "cccc\n", "app.log.1": "aaaa\nbbbb\n"},
+		},
+		{
+			name:     "drops the oldest",
+			writes:   []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"},
+			maxFiles: 2,
+			want:     map[string]string{"app.log": "dddddddd\n", "app.log.1": "cccccccc\n", "app.log.2": "bbbbbbbb\n"},
+		},
+		{
+			name:     "oversized write",
+			writes:   []string{"a\n", strings.Repeat("b", 20) + "\n"},
+			maxFiles: 1,
+			want:     map[string]string{"app.log": strings.Repeat("b", 20) + "\n", "app.log.1": "a\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := OpenRotatingFile(dir, "app.log", 10, tt.maxFiles)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.writes {
+				if _, err := f.Write([]byte(w)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.Close()
+
+			got := make(map[string]string)
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				data, _ := os.ReadFile(filepath.Join(dir, e.Name()))
+				got[e.Name()] = string(data)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	dir := t.TempDir()
+	for _, text := range []string{"first\n", "second\n"} {
+		f, err := OpenRotatingFile(dir, "app.log", 100, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(text))
+		f.Close()
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("app.log = %q, want both writes", data)
+	}
+}
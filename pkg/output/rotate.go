@@ -0,0 +1,109 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultLogSize is the size at which a log file is rotated
+	DefaultLogSize = 10 << 20
+	// DefaultLogFiles is the number of rotated log files kept
+	DefaultLogFiles = 5
+)
+
+// RotatingFile is a log file that is renamed to name.1, name.2, ... once it
+// reaches its maximum size. The oldest rotated file is deleted.
+type RotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// OpenRotatingFile opens or creates name in dir for appending
+func OpenRotatingFile(dir, name string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultLogSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultLogFiles
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	r := &RotatingFile{
+		path:     filepath.Join(dir, name),
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write implements io.Writer, rotating the file first if p does not fit
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file
+func (r *RotatingFile) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open opens the current file and records its size
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files up by one and starts a new current file
+func (r *RotatingFile) rotate() error {
+	r.file.Close()
+	r.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return r.open()
+}
@@ -0,0 +1,86 @@
+package pattern
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Match reports whether a slash separated path relative to the project
+// matches a glob pattern. A pattern without a slash matches the base name
+// at any depth, "**" matches any number of directories and a trailing
+// slash matches everything below a directory.
+func Match(pattern, name string) bool {
+	name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "./")
+
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments, expanding "**" to zero or more segments
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// List is an ordered list of patterns. A pattern starting with "!" excludes
+// paths matched by earlier patterns.
+type List []string
+
+// Match reports whether the last pattern matching name includes it
+func (l List) Match(name string) bool {
+	matched := false
+	for _, p := range l {
+		if exclude, ok := strings.CutPrefix(p, "!"); ok {
+			if matched && Match(exclude, name) {
+				matched = false
+			}
+		} else if !matched && Match(p, name) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// Rel returns file relative to root as a slash separated path, or file
+// itself when it lies outside root
+func Rel(root, file string) string {
+	absRoot, err1 := filepath.Abs(root)
+	absFile, err2 := filepath.Abs(file)
+	if err1 != nil || err2 != nil {
+		return filepath.ToSlash(file)
+	}
+
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+	"hotreloader/pkg/pattern"
+	"hotreloader/pkg/process"
+)
+
+// DefaultTimeout is used for command stages that do not set their own timeout
+const DefaultTimeout = 10 * time.Minute
+
+// outputLines is the number of output lines kept for a failed command
+const outputLines = 20
+
+// Stage is a step of the pipeline
+type Stage struct {
+	Name     string
+	Type     string
+	Command  string
+	Patterns pattern.List
+	After    []string
+	Advisory bool
+	Timeout  time.Duration
+}
+
+// Builtin runs a stage implemented by the reloader, such as the build or
+// the restart
+type Builtin func() error
+
+// Pipeline runs stages in dependency order, in parallel where possible
+type Pipeline struct {
+	dir    string
+	stages []Stage
+}
+
+// New creates a pipeline from validated stage configs. Commands run in dir.
+func New(dir string, cfgs []config.StageConfig) *Pipeline {
+	p := &Pipeline{dir: dir}
+	for _, c := range cfgs {
+		stageType := c.Type
+		if stageType == "" {
+			stageType = config.StageCommand
+		}
+		timeout := c.Timeout.Duration
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		p.stages = append(p.stages, Stage{
+			Name:     c.Name,
+			Type:     stageType,
+			Command:  c.Command,
+			Patterns: c.Patterns,
+			After:    c.After,
+			Advisory: c.Advisory,
+			Timeout:  timeout,
+		})
+	}
+	return p
+}
+
+// String lists the stage names
+func (p *Pipeline) String() string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Run runs the pipeline for a change to trigger, or every stage when
+// trigger is empty. Builtins implement the stages of the other types.
+// finished is called as each stage finishes; results are in config order.
+// A required stage failing stops the pipeline: stages that have not
+// started are skipped, while stages already running finish.
+func (p *Pipeline) Run(trigger string, env []string, builtins map[string]Builtin, finished func(events.StageResult)) ([]events.StageResult, error) {
+	results := make([]events.StageResult, len(p.stages))
+	done := make(map[string]chan struct{}, len(p.stages))
+	for _, s := range p.stages {
+		done[s.Name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	var failed error
+	var wg sync.WaitGroup
+
+	for i, s := range p.stages {
+		wg.Add(1)
+		go func(i int, s Stage) {
+			defer wg.Done()
+			defer close(done[s.Name])
+
+			for _, dep := range s.After {
+				<-done[dep]
+			}
+
+			mu.Lock()
+			stopped := failed
+			mu.Unlock()
+
+			result := p.runStage(s, trigger, env, builtins, stopped)
+
+			mu.Lock()
+			results[i] = result
+			if result.Status == events.StageFailed && !s.Advisory && failed == nil {
+				failed = fmt.Errorf("stage %s failed: %w", s.Name, result.Err)
+			}
+			mu.Unlock()
+
+			if finished != nil {
+				finished(result)
+			}
+		}(i, s)
+	}
+
+	wg.Wait()
+	return results, failed
+}
+
+// runStage runs one stage unless the pipeline was stopped or the change
+// does not match its patterns
+func (p *Pipeline) runStage(s Stage, trigger string, env []string, builtins map[string]Builtin, stopped error) events.StageResult {
+	result := events.StageResult{Name: s.Name, Advisory: s.Advisory}
+
+	switch {
+	case stopped != nil:
+		result.Status = events.StageSkipped
+		result.Reason = "pipeline stopped"
+		return result
+	case trigger != "" && len(s.Patterns) > 0 && !s.Patterns.Match(pattern.Rel(p.dir, trigger)):
+		result.Status = events.StageSkipped
+		result.Reason = "no matching change"
+		return result
+	}
+
+	start := time.Now()
+	var err error
+	if s.Type == config.StageCommand {
+		result.Output, err = p.runCommand(s, env)
+	} else if builtin, ok := builtins[s.Type]; ok {
+		err = builtin()
+	} else {
+		result.Status = events.StageSkipped
+		result.Reason = "not available"
+		return result
+	}
+
+	result.Duration = time.Since(start)
+	result.Status = events.StagePassed
+	if err != nil {
+		result.Status = events.StageFailed
+		result.Err = err
+	}
+	return result
+}
+
+// runCommand runs a command stage, returning the tail of its output when
+// it fails
+func (p *Pipeline) runCommand(s Stage, env []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	// A timed out stage is killed with every command it started, and
+	// output pipes they still hold do not keep Wait blocked
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.Command)
+	process.KillGroupOnCancel(cmd)
+	cmd.WaitDelay = time.Second
+	cmd.Dir = p.dir
+	cmd.Env = append(append(os.Environ(), env...), "HOTRELOADER_STAGE="+s.Name)
+
+	// Stages run in parallel, so output is kept rather than interleaved
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", s.Timeout)
+	}
+	if err == nil {
+		return nil, nil
+	}
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) > outputLines {
+		lines = lines[len(lines)-outputLines:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		lines = nil
+	}
+	return lines, err
+}
@@ -0,0 +1,83 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+)
+
+func TestRun(t *testing.T) {
+	timeout := config.Duration{Duration: 200 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		stages  []config.StageConfig
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "stages pass in order",
+			stages: []config.StageConfig{
+				{Name: "gen", Command: "true"},
+				{Name: "test", Command: "true", After: []string{"gen"}},
+			},
+			want: []string{events.StagePassed, events.StagePassed},
+		},
+		{
+			name: "required failure skips later stages",
+			stages: []config.StageConfig{
+				{Name: "lint", Command: "exit 1"},
+				{Name: "test", Command: "true", After: []string{"lint"}},
+			},
+			want:    []string{events.StageFailed, events.StageSkipped},
+			wantErr: "stage lint failed",
+		},
+		{
+			name: "advisory failure continues",
+			stages: []config.StageConfig{
+				{Name: "lint", Command: "exit 1", Advisory: true},
+				{Name: "test", Command: "true", After: []string{"lint"}},
+			},
+			want: []string{events.StageFailed, events.StagePassed},
+		},
+		{
+			name: "timeout kills commands holding the output",
+			stages: []config.StageConfig{
+				{Name: "serve", Command: "sleep 30 & wait", Timeout: timeout},
+			},
+			want:    []string{events.StageFailed},
+			wantErr: "timed out after 200ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(t.TempDir(), tt.stages)
+
+			start := time.Now()
+			results, err := p.Run("", nil, nil, nil)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Run took %v", elapsed)
+			}
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Run error = %v, want %q", err, tt.wantErr)
+			}
+
+			if len(results) != len(tt.want) {
+				t.Fatalf("%d stage results, want %d", len(results), len(tt.want))
+			}
+			for i, r := range results {
+				if r.Status != tt.want[i] {
+					t.Errorf("stage %s = %s, want %s", r.Name, r.Status, tt.want[i])
+				}
+			}
+		})
+	}
+}
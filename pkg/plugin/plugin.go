@@ -3,6 +3,7 @@ package plugin
 import (
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -14,6 +15,14 @@ type BuildPlugin interface {
 	GetBuildTime() time.Duration
 }
 
+// ArtifactPlugin is implemented by plugins that produce a runnable binary
+// and can write it to a caller chosen path
+type ArtifactPlugin interface {
+	BuildPlugin
+	SetOutputPath(path string)
+	OutputPath() string
+}
+
 // WebpackPlugin implements Webpack integration
 type WebpackPlugin struct {
 	configPath string
@@ -107,6 +116,8 @@ func (v *VitePlugin) GetBuildTime() time.Duration {
 // GoPlugin implements Go build integration
 type GoPlugin struct {
 	modulePath string
+	outputPath string
+	tags       []string
 	lastBuildTime time.Duration
 }
 
@@ -114,9 +125,26 @@ type GoPlugin struct {
 func NewGoPlugin(modulePath string) *GoPlugin {
 	return &GoPlugin{
 		modulePath: modulePath,
+		outputPath: "/tmp/hotreload_output",
 	}
 }
 
+// SetOutputPath sets where the next build writes its binary
+func (g *GoPlugin) SetOutputPath(path string) {
+	g.outputPath = path
+}
+
+// SetTags sets the extra build tags builds are made with. Builds are
+// always for the host, as they are run.
+func (g *GoPlugin) SetTags(tags []string) {
+	g.tags = tags
+}
+
+// OutputPath returns where builds write their binary
+func (g *GoPlugin) OutputPath() string {
+	return g.outputPath
+}
+
 // Name returns the plugin name
 func (g *GoPlugin) Name() string {
 	return "go"
@@ -133,7 +161,11 @@ func (g *GoPlugin) Build(files []string) error {
 	start := time.Now()
 
 	// Build from the project directory
-	cmd := exec.Command("go", "build", "-o", "/tmp/hotreload_output", ".")
+	args := []string{"build", "-o", g.outputPath}
+	if len(g.tags) > 0 {
+		args = append(args, "-tags", strings.Join(g.tags, ","))
+	}
+	cmd := exec.Command("go", append(args, ".")...)
 	cmd.Dir = g.modulePath // Set working directory to project root
 
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoPluginBuild(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":      "module example.com/app\n\ngo 1.22\n",
+		"main.go":     "package main\n\nfunc main() { println(greeting) }\n",
+		"tagged.go":   "//go:build special\n\npackage main\n\nconst greeting = \"special\"\n",
+		"untagged.go": "//go:build !special\n\npackage main\n\nconst greeting = \"plain\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{name: "no tags", want: "plain"},
+		{name: "tags", tags: []string{"special"}, want: "special"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGoPlugin(dir)
+			g.SetOutputPath(filepath.Join(t.TempDir(), "app"))
+			g.SetTags(tt.tags)
+			if err := g.Build(nil); err != nil {
+				t.Fatal(err)
+			}
+
+			// The binary is built to run on this machine
+			out, err := exec.Command(g.OutputPath()).CombinedOutput()
+			if err != nil {
+				t.Fatalf("running build: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("build printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
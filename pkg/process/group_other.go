@@ -0,0 +1,34 @@
+//go:build !unix
+
+package process
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op where process groups are not supported
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup signals only the process itself
+func signalGroup(pid int, sig os.Signal) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(sig)
+}
+
+// killGroup kills only the process itself
+func killGroup(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Kill()
+}
+
+// groupMembers cannot track descendants on this platform
+func groupMembers(pid int) ([]int, bool) {
+	return nil, false
+}
@@ -0,0 +1,76 @@
+//go:build unix
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup starts the command as the leader of a new process group,
+// so that it and everything it forks can be signalled together
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalGroup delivers a signal to every process in the group led by pid
+func signalGroup(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		s = syscall.SIGINT
+	}
+	return syscall.Kill(-pid, s)
+}
+
+// killGroup sends SIGKILL to every process in the group led by pid
+func killGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// groupMembers returns the processes still alive in the group led by pid.
+// It reports whether any exist even when their PIDs cannot be listed.
+func groupMembers(pid int) ([]int, bool) {
+	if err := syscall.Kill(-pid, 0); err != nil {
+		return nil, false
+	}
+
+	// Listing members needs procfs; elsewhere only existence is known
+	stats, _ := filepath.Glob("/proc/[0-9]*/stat")
+	var members []int
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		// The fields after the parenthesised command are: state ppid pgrp
+		stat := string(data)
+		end := strings.LastIndexByte(stat, ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 3 || fields[2] != strconv.Itoa(pid) {
+			continue
+		}
+		// Zombies are already dead and only wait to be reaped
+		if fields[0] == "Z" {
+			continue
+		}
+		member, err := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		if err == nil {
+			members = append(members, member)
+		}
+	}
+
+	if len(members) == 0 && len(stats) > 0 {
+		return nil, false
+	}
+	return members, true
+}
@@ -0,0 +1,109 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listenFdsStart is the first inherited descriptor in the systemd
+// socket activation convention
+const listenFdsStart = 3
+
+// listener is a listening socket owned by the controller
+type listener struct {
+	name string
+	file *os.File
+}
+
+// Listen opens listening sockets that every process started by the
+// controller inherits as file descriptors 3 and up, following the systemd
+// LISTEN_FDS convention. Addresses are "host:port" for TCP or carry a
+// network prefix such as "tcp://:8080" or "unix:///tmp/app.sock".
+// Because the sockets stay open across restarts, a new process can start
+// accepting connections before the old one is stopped.
+func (c *Controller) Listen(addrs []string) error {
+	for _, addr := range addrs {
+		network, address := "tcp", addr
+		if i := strings.Index(addr, "://"); i >= 0 {
+			network, address = addr[:i], addr[i+3:]
+		}
+
+		l, err := net.Listen(network, address)
+		if err != nil {
+			c.CloseListeners()
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+
+		f, err := listenerFile(l)
+		l.Close()
+		if err != nil {
+			c.CloseListeners()
+			return fmt.Errorf("failed to hand off %s: %w", addr, err)
+		}
+
+		c.listeners = append(c.listeners, listener{name: addr, file: f})
+	}
+
+	return nil
+}
+
+// HandsOff reports whether processes inherit listening sockets, in which
+// case a replacement can be started before the old process is stopped
+func (c *Controller) HandsOff() bool {
+	return len(c.listeners) > 0
+}
+
+// CloseListeners releases the listening sockets
+func (c *Controller) CloseListeners() {
+	for _, l := range c.listeners {
+		l.file.Close()
+	}
+	c.listeners = nil
+}
+
+// inheritListeners passes the listening sockets to cmd. LISTEN_PID must
+// name the child itself, which is only known after the fork, so a shell
+// exports its own PID and then execs the command in its place. The started
+// process, its PID and LISTEN_PID are therefore the command's own.
+func (c *Controller) inheritListeners(cmd *exec.Cmd) {
+	if len(c.listeners) == 0 {
+		return
+	}
+
+	names := make([]string, len(c.listeners))
+	for i, l := range c.listeners {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, l.file)
+		names[i] = l.name
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env,
+		"LISTEN_FDS="+strconv.Itoa(len(c.listeners)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+	)
+
+	// sh -c 'script' argv0 args... runs script with $0 and $@ set
+	args := append([]string{"sh", "-c", `export LISTEN_PID=$$; exec "$0" "$@"`, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	cmd.Args = args
+}
+
+// listenerFile returns a duplicate descriptor of a listening socket
+func listenerFile(l net.Listener) (*os.File, error) {
+	switch ln := l.(type) {
+	case *net.TCPListener:
+		return ln.File()
+	case *net.UnixListener:
+		// Keep the socket file when the original listener is closed
+		ln.SetUnlinkOnClose(false)
+		return ln.File()
+	default:
+		return nil, fmt.Errorf("unsupported listener type %T", l)
+	}
+}
@@ -0,0 +1,192 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Process is a started command running as the leader of its own process
+// group. Its exit status is collected in the background.
+type Process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+// Pid returns the process ID, which is also the process group ID
+func (p *Process) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+// Done is closed once the process has exited
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Exited reports whether the process has exited
+func (p *Process) Exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error from waiting on the process, valid once Done is closed
+func (p *Process) Err() error {
+	return p.err
+}
+
+// Signal delivers a signal to the process group
+func (p *Process) Signal(sig os.Signal) error {
+	return signalGroup(p.Pid(), sig)
+}
+
+// ExitStatus returns the exit code and the name of the terminating signal,
+// which is empty when the process exited normally
+func (p *Process) ExitStatus() (int, string) {
+	state := p.cmd.ProcessState
+	if state == nil {
+		return -1, ""
+	}
+
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return state.ExitCode(), ws.Signal().String()
+	}
+	return state.ExitCode(), ""
+}
+
+// KillGroupOnCancel starts cmd, created with exec.CommandContext, as the
+// leader of a new process group and kills the whole group when its context
+// ends, so commands started by a shell do not outlive it
+func KillGroupOnCancel(cmd *exec.Cmd) {
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killGroup(cmd.Process.Pid)
+	}
+}
+
+// Controller starts processes in their own process group and stops them,
+// together with everything they forked, using a configurable stop sequence
+type Controller struct {
+	sequence  StopSequence
+	reapWait  time.Duration
+	listeners []listener
+}
+
+// NewController creates a controller that stops processes with seq
+func NewController(seq StopSequence) *Controller {
+	if len(seq) == 0 {
+		seq = MustParseStopSequence(DefaultStopSequence)
+	}
+	return &Controller{
+		sequence: seq,
+		reapWait: time.Second,
+	}
+}
+
+// Sequence returns the stop sequence of the controller
+func (c *Controller) Sequence() StopSequence {
+	return c.sequence
+}
+
+// Start starts cmd as the leader of a new process group, passing it the
+// controller's listening sockets
+func (c *Controller) Start(cmd *exec.Cmd) (*Process, error) {
+	setProcessGroup(cmd)
+	c.inheritListeners(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &Process{
+		cmd:  cmd,
+		done: make(chan struct{}),
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// Stop runs the stop sequence against the process group until the process
+// exits. If it is still running when the sequence ends, the group is killed.
+// Descendants left in the group afterwards are reaped.
+func (c *Controller) Stop(p *Process) {
+	if !p.Exited() {
+		fmt.Printf("Stopping process group (PID: %d)...\n", p.Pid())
+		if c.runSequence(p) {
+			fmt.Println("Process stopped")
+		} else {
+			fmt.Println("Stop sequence finished, force killing process group...")
+			killGroup(p.Pid())
+			<-p.done
+		}
+	}
+
+	c.Reap(p)
+}
+
+// runSequence executes the stop steps and reports whether the process
+// exited before the sequence ran out
+func (c *Controller) runSequence(p *Process) bool {
+	for _, step := range c.sequence {
+		if p.Exited() {
+			return true
+		}
+
+		if step.Signal != nil {
+			fmt.Printf("  sending %s\n", step.Name)
+			if err := signalGroup(p.Pid(), step.Signal); err != nil {
+				// The group is gone or cannot be signalled; move on
+				continue
+			}
+			if step.Name == "SIGKILL" {
+				<-p.done
+				return true
+			}
+			continue
+		}
+
+		timer := time.NewTimer(step.Wait)
+		select {
+		case <-p.done:
+			timer.Stop()
+			return true
+		case <-timer.C:
+		}
+	}
+
+	return p.Exited()
+}
+
+// Reap kills descendants left in the process group after its leader
+// exited, so they do not keep holding ports for the next start
+func (c *Controller) Reap(p *Process) {
+	pid := p.Pid()
+
+	// Give children that got the same signal a moment to finish
+	deadline := time.Now().Add(c.reapWait)
+	members, alive := groupMembers(pid)
+	for alive && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		members, alive = groupMembers(pid)
+	}
+	if !alive {
+		return
+	}
+
+	if len(members) > 0 {
+		fmt.Printf("⚠️  %d leftover process(es) in group %d after exit: %v, killing...\n", len(members), pid, members)
+	} else {
+		fmt.Printf("⚠️  Leftover processes in group %d after exit, killing...\n", pid)
+	}
+	killGroup(pid)
+}
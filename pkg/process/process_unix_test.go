@@ -0,0 +1,234 @@
+//go:build unix
+
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/internal/proctest"
+)
+
+// startScript starts a shell script under the controller and returns the
+// process and the first line it prints
+func startScript(t *testing.T, c *Controller, script string) (*Process, string) {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Start(cmd)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { killGroup(p.Pid()) })
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading first line: %v", err)
+	}
+	return p, strings.TrimSpace(line)
+}
+
+func TestStopSequence(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		sequence   string
+		wantSignal string
+		minElapsed time.Duration
+	}{
+		{
+			name:       "first signal stops",
+			script:     "echo ready; exec sleep 30",
+			sequence:   "SIGINT, wait 1s, SIGKILL",
+			wantSignal: "interrupt",
+		},
+		{
+			name:       "ignored signal escalates to SIGKILL",
+			script:     "trap '' INT; echo ready; exec sleep 30",
+			sequence:   "SIGINT, wait 200ms, SIGKILL",
+			wantSignal: "killed",
+			minElapsed: 200 * time.Millisecond,
+		},
+		{
+			name:       "ignored signal escalates to the next signal",
+			script:     "trap '' INT; echo ready; exec sleep 30",
+			sequence:   "SIGINT, wait 200ms, SIGTERM, wait 1s, SIGKILL",
+			wantSignal: "terminated",
+			minElapsed: 200 * time.Millisecond,
+		},
+		{
+			name:       "exhausted sequence kills the group",
+			script:     "trap '' INT; echo ready; exec sleep 30",
+			sequence:   "SIGINT, wait 200ms",
+			wantSignal: "killed",
+			minElapsed: 200 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(MustParseStopSequence(tt.sequence))
+			p, _ := startScript(t, c, tt.script)
+
+			start := time.Now()
+			c.Stop(p)
+			elapsed := time.Since(start)
+
+			if !p.Exited() {
+				t.Fatal("process still running after Stop")
+			}
+			if _, signal := p.ExitStatus(); signal != tt.wantSignal {
+				t.Errorf("terminating signal = %q, want %q", signal, tt.wantSignal)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("stopped after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+		})
+	}
+}
+
+func TestStopKillsGrandchildren(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence string
+	}{
+		// Background jobs of sh ignore SIGINT, so the grandchild outlives
+		// its parent and is reaped through the group
+		{name: "leader exits on the signal", sequence: "SIGINT, wait 1s, SIGKILL"},
+		{name: "group killed", sequence: "SIGKILL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(MustParseStopSequence(tt.sequence))
+			c.reapWait = 100 * time.Millisecond
+			p, line := startScript(t, c, "sleep 30 & echo $!; wait")
+
+			grandchild, err := strconv.Atoi(line)
+			if err != nil {
+				t.Fatalf("grandchild PID %q: %v", line, err)
+			}
+			if !proctest.Alive(grandchild) {
+				t.Fatalf("grandchild %d not running", grandchild)
+			}
+
+			c.Stop(p)
+
+			if !proctest.WaitGone(grandchild, 2*time.Second) {
+				t.Errorf("grandchild %d survived Stop", grandchild)
+			}
+		})
+	}
+}
+
+func TestKillGroupOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	KillGroupOnCancel(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the background PID: %v", err)
+	}
+	background, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("background PID %q: %v", line, err)
+	}
+
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Error("Wait succeeded, want the killed shell's error")
+	}
+	if !proctest.WaitGone(background, 2*time.Second) {
+		t.Errorf("background job %d outlived the cancelled command", background)
+	}
+}
+
+// listenerHelperEnv makes the test binary act as an application started
+// with inherited listeners
+const listenerHelperEnv = "HOTRELOADER_LISTENER_HELPER"
+
+// TestListenerHelper is the application of TestListenerHandOff. It reports
+// its PID, the LISTEN_* variables and the address of descriptor 3.
+func TestListenerHelper(t *testing.T) {
+	if os.Getenv(listenerHelperEnv) == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	addr := "none"
+	if l, err := net.FileListener(os.NewFile(listenFdsStart, "listener")); err == nil {
+		addr = l.Addr().String()
+	}
+	fmt.Printf("%d %s %s %s %s\n", os.Getpid(), os.Getenv("LISTEN_PID"),
+		os.Getenv("LISTEN_FDS"), os.Getenv("LISTEN_FDNAMES"), addr)
+	os.Exit(0)
+}
+
+func TestListenerHandOff(t *testing.T) {
+	c := NewController(nil)
+	if err := c.Listen([]string{"127.0.0.1:0"}); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer c.CloseListeners()
+
+	l, err := net.FileListener(c.listeners[0].file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAddr := l.Addr().String()
+	l.Close()
+
+	var out bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListenerHelper$")
+	cmd.Env = append(os.Environ(), listenerHelperEnv+"=1")
+	cmd.Stdout = &out
+
+	p, err := c.Start(cmd)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	<-p.Done()
+	if p.Err() != nil {
+		t.Fatalf("helper failed: %v\n%s", p.Err(), out.String())
+	}
+
+	fields := strings.Fields(out.String())
+	if len(fields) != 5 {
+		t.Fatalf("helper output %q", out.String())
+	}
+	pid, listenPID, fds, names, addr := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	if pid != strconv.Itoa(p.Pid()) {
+		t.Errorf("application PID = %s, want the started process %d", pid, p.Pid())
+	}
+	if listenPID != pid {
+		t.Errorf("LISTEN_PID = %s, want the application PID %s", listenPID, pid)
+	}
+	if fds != "1" || names != "127.0.0.1:0" {
+		t.Errorf("LISTEN_FDS = %s, LISTEN_FDNAMES = %s", fds, names)
+	}
+	if addr != wantAddr {
+		t.Errorf("descriptor 3 listens on %s, want %s", addr, wantAddr)
+	}
+}
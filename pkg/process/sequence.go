@@ -0,0 +1,101 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultStopSequence interrupts the process and kills it after 2 seconds
+const DefaultStopSequence = "SIGINT, wait 2s, SIGKILL"
+
+// Step is one action of a stop sequence: either send Signal or wait up to
+// Wait for the process to exit
+type Step struct {
+	Signal os.Signal
+	Name   string
+	Wait   time.Duration
+}
+
+// String returns the step as written in a stop sequence
+func (s Step) String() string {
+	if s.Signal != nil {
+		return s.Name
+	}
+	return "wait " + s.Wait.String()
+}
+
+// StopSequence is the ordered list of steps used to stop a process
+type StopSequence []Step
+
+// String returns the sequence in its textual form
+func (seq StopSequence) String() string {
+	parts := make([]string, len(seq))
+	for i, step := range seq {
+		parts[i] = step.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseStopSequence parses a comma separated sequence of signals and waits,
+// for example "SIGTERM, wait 10s, SIGKILL". Signal names may omit the SIG
+// prefix and a wait may be written as a bare duration.
+func ParseStopSequence(text string) (StopSequence, error) {
+	var seq StopSequence
+
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(strings.ToLower(part), "wait"); ok {
+			d, err := time.ParseDuration(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("invalid wait %q: %w", part, err)
+			}
+			seq = append(seq, Step{Wait: d})
+			continue
+		}
+
+		if d, err := time.ParseDuration(part); err == nil {
+			seq = append(seq, Step{Wait: d})
+			continue
+		}
+
+		sig, name, err := ParseSignal(part)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, Step{Signal: sig, Name: name})
+	}
+
+	if len(seq) == 0 {
+		return nil, fmt.Errorf("empty stop sequence")
+	}
+	return seq, nil
+}
+
+// ParseSignal parses a signal name such as "SIGHUP" or "hup", returning the
+// signal and its canonical name
+func ParseSignal(text string) (os.Signal, string, error) {
+	name := strings.ToUpper(strings.TrimSpace(text))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown signal %q", text)
+	}
+	return sig, name, nil
+}
+
+// MustParseStopSequence is like ParseStopSequence but panics on error
+func MustParseStopSequence(text string) StopSequence {
+	seq, err := ParseStopSequence(text)
+	if err != nil {
+		panic(err)
+	}
+	return seq
+}
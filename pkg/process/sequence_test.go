@@ -0,0 +1,40 @@
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStopSequence(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "SIGINT, wait 2s, SIGKILL", want: "SIGINT, wait 2s, SIGKILL"},
+		{text: "term, 500ms, kill", want: "SIGTERM, wait 500ms, SIGKILL"},
+		{text: " sighup ,, WAIT 1m ", want: "SIGHUP, wait 1m0s"},
+		{text: "", wantErr: true},
+		{text: "SIGFOO", wantErr: true},
+		{text: "wait forever", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			seq, err := ParseStopSequence(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStopSequence(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			}
+			if err == nil && seq.String() != tt.want {
+				t.Errorf("ParseStopSequence(%q) = %q, want %q", tt.text, seq, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStopSequenceSteps(t *testing.T) {
+	seq := MustParseStopSequence("SIGTERM, wait 3s")
+	if len(seq) != 2 || seq[0].Signal == nil || seq[0].Name != "SIGTERM" || seq[1].Signal != nil || seq[1].Wait != 3*time.Second {
+		t.Errorf("steps = %#v", seq)
+	}
+}
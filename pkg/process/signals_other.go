@@ -0,0 +1,17 @@
+//go:build !unix
+
+package process
+
+import (
+	"os"
+	"syscall"
+)
+
+// signals maps the names accepted in stop sequences to signals
+var signals = map[string]os.Signal{
+	"SIGINT":  os.Interrupt,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGKILL": os.Kill,
+}
@@ -0,0 +1,354 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+)
+
+const (
+	// DefaultHoldTimeout is how long requests wait for a rebuild or restart
+	DefaultHoldTimeout = 30 * time.Second
+	// maxBufferedBody is the largest request body kept for retries
+	maxBufferedBody = 10 << 20
+	// retryInterval is the delay between attempts while the app is unreachable
+	retryInterval = 250 * time.Millisecond
+)
+
+// state is whether requests can currently be forwarded
+type state int
+
+const (
+	stateReady state = iota
+	stateHolding
+	stateFailed
+)
+
+// Proxy is a reverse proxy in front of the application. While a build or
+// restart is in progress it holds incoming requests, up to a timeout, and
+// forwards them once the application is ready. After a failed build it
+// answers with a 503 page showing the build error.
+type Proxy struct {
+	listen      string
+	target      *url.URL
+	holdTimeout time.Duration
+	process     string
+	reverse     *httputil.ReverseProxy
+	server      *http.Server
+	mounts      []mount
+	pageScript  string
+
+	mu       sync.Mutex
+	state    state
+	err      error
+	readyPID int
+	changed  chan struct{}
+}
+
+// mount is a handler served by the proxy under a path prefix
+type mount struct {
+	prefix  string
+	handler http.Handler
+}
+
+// dialError records a failure to reach the application for one attempt
+type dialError struct {
+	err error
+}
+
+// New creates a proxy from the proxy config
+func New(cfg config.ProxyConfig) (*Proxy, error) {
+	target, err := url.Parse(cfg.Target)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid proxy target %q", cfg.Target)
+	}
+
+	holdTimeout := cfg.HoldTimeout.Duration
+	if holdTimeout <= 0 {
+		holdTimeout = DefaultHoldTimeout
+	}
+
+	p := &Proxy{
+		listen:      cfg.Listen,
+		target:      target,
+		holdTimeout: holdTimeout,
+		process:     cfg.Process,
+		changed:     make(chan struct{}),
+	}
+
+	p.reverse = httputil.NewSingleHostReverseProxy(target)
+	p.reverse.ErrorHandler = p.handleProxyError
+	p.server = &http.Server{
+		Addr:    cfg.Listen,
+		Handler: p,
+	}
+
+	return p, nil
+}
+
+// Start serves the proxy in the background
+func (p *Proxy) Start() error {
+	l, err := net.Listen("tcp", p.listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", p.listen, err)
+	}
+	go p.server.Serve(l)
+	return nil
+}
+
+// Close stops the proxy
+func (p *Proxy) Close() error {
+	return p.server.Close()
+}
+
+// ReverseProxy exposes the underlying reverse proxy so responses can be
+// rewritten by other integrations
+func (p *Proxy) ReverseProxy() *httputil.ReverseProxy {
+	return p.reverse
+}
+
+// Handle serves paths starting with prefix from the proxy itself instead
+// of forwarding them to the application
+func (p *Proxy) Handle(prefix string, handler http.Handler) {
+	p.mounts = append(p.mounts, mount{prefix: prefix, handler: handler})
+}
+
+// SetPageScript sets markup added to pages generated by the proxy, such as
+// the build error page
+func (p *Proxy) SetPageScript(script string) {
+	p.pageScript = script
+}
+
+// HandleEvent tracks builds and restarts to decide whether to hold requests
+func (p *Proxy) HandleEvent(e events.Event) {
+	switch ev := e.(type) {
+	case events.BuildStarted:
+		p.setState(stateHolding, nil)
+	case events.BuildSucceeded:
+		p.setState(stateReady, nil)
+	case events.BuildFailed:
+		p.setState(stateFailed, ev.Err)
+	case events.ProcessStopping:
+		if p.tracks(ev.Name) {
+			p.processGone(ev.PID)
+		}
+	case events.ProcessExited:
+		if p.tracks(ev.Name) {
+			p.processGone(ev.PID)
+		}
+	case events.ProcessReady:
+		if !p.tracks(ev.Name) {
+			return
+		}
+		p.mu.Lock()
+		p.readyPID = ev.PID
+		p.mu.Unlock()
+		p.setState(stateReady, nil)
+	case events.ProcessStartFailed:
+		if !p.tracks(ev.Name) {
+			return
+		}
+		// With listener hand-off the previous process may still be serving
+		p.mu.Lock()
+		serving := p.readyPID != 0
+		p.mu.Unlock()
+		if !serving {
+			p.setState(stateFailed, ev.Err)
+		}
+	}
+}
+
+// Essential reports whether an event changes whether requests are held,
+// so the bus never drops it. A lost ProcessReady would hold every request
+// until the next build.
+func (p *Proxy) Essential(e events.Event) bool {
+	switch e.(type) {
+	case events.BuildStarted, events.BuildSucceeded, events.BuildFailed,
+		events.ProcessStopping, events.ProcessExited, events.ProcessReady, events.ProcessStartFailed:
+		return true
+	}
+	return false
+}
+
+// tracks reports whether events of the named process concern the target
+func (p *Proxy) tracks(name string) bool {
+	return p.process == "" || p.process == name
+}
+
+// processGone holds requests when the process serving them goes away
+func (p *Proxy) processGone(pid int) {
+	p.mu.Lock()
+	serving := p.readyPID == pid
+	if serving {
+		p.readyPID = 0
+	}
+	p.mu.Unlock()
+
+	if serving {
+		p.setState(stateHolding, nil)
+	}
+}
+
+// setState changes the proxy state and wakes up held requests
+func (p *Proxy) setState(s state, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state = s
+	p.err = err
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// snapshot returns the current state and a channel closed on the next change
+func (p *Proxy) snapshot() (state, error, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.err, p.changed
+}
+
+// ServeHTTP serves the proxy's own handlers and forwards everything else
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, m := range p.mounts {
+		if strings.HasPrefix(r.URL.Path, m.prefix) {
+			m.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	p.forward(w, r)
+}
+
+// forward forwards a request, holding it while the application is being
+// rebuilt, restarted or cannot be reached yet
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	body, err := bufferBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	deadline := time.NewTimer(p.holdTimeout)
+	defer deadline.Stop()
+
+	for {
+		st, buildErr, changed := p.snapshot()
+
+		switch st {
+		case stateFailed:
+			writeErrorPage(w, buildErr, p.pageScript)
+			return
+
+		case stateHolding:
+			select {
+			case <-changed:
+				continue
+			case <-deadline.C:
+				http.Error(w, "Timed out waiting for the application to rebuild", http.StatusGatewayTimeout)
+				return
+			case <-r.Context().Done():
+				return
+			}
+
+		case stateReady:
+			attempt := &dialError{}
+			req := r.WithContext(withAttempt(r.Context(), attempt))
+			if body != nil {
+				req.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			p.reverse.ServeHTTP(w, req)
+			if attempt.err == nil {
+				return
+			}
+
+			// Nothing was written; retry until the app comes up
+			select {
+			case <-changed:
+			case <-time.After(retryInterval):
+			case <-deadline.C:
+				http.Error(w, fmt.Sprintf("Application unreachable: %v", attempt.err), http.StatusBadGateway)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// handleProxyError records dial failures for a retry and reports other errors
+func (p *Proxy) handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		if attempt := attemptFrom(r.Context()); attempt != nil {
+			attempt.err = err
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("Proxy error: %v", err), http.StatusBadGateway)
+}
+
+// bufferBody reads the request body so it can be replayed on retries
+func bufferBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBufferedBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBufferedBody {
+		return nil, fmt.Errorf("request body too large to hold during rebuild")
+	}
+	return body, nil
+}
+
+// writeErrorPage answers with a 503 page showing the build error
+func writeErrorPage(w http.ResponseWriter, err error, script string) {
+	msg := "unknown error"
+	if err != nil {
+		msg = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Build failed</title></head>
+<body style="font-family: sans-serif; margin: 2em;">
+<h1 style="color: #c00;">Build failed</h1>
+<p>The application will be served again after the next successful build.</p>
+<pre style="background: #f6f6f6; padding: 1em; overflow: auto;">%s</pre>
+%s
+</body>
+</html>
+`, html.EscapeString(msg), script)
+}
+
+// attemptKey is the context key carrying the current forwarding attempt
+type attemptKey struct{}
+
+// withAttempt attaches an attempt to a request context
+func withAttempt(ctx context.Context, attempt *dialError) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptFrom returns the attempt attached to a request context
+func attemptFrom(ctx context.Context) *dialError {
+	attempt, _ := ctx.Value(attemptKey{}).(*dialError)
+	return attempt
+}
@@ -0,0 +1,242 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+)
+
+const testHoldTimeout = 300 * time.Millisecond
+
+// newTestProxy returns a proxy forwarding to target and serving through an
+// httptest server
+func newTestProxy(t *testing.T, target, process string) (*Proxy, *httptest.Server) {
+	t.Helper()
+	p, err := New(config.ProxyConfig{
+		Target:      target,
+		HoldTimeout: config.Duration{Duration: testHoldTimeout},
+		Process:     process,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	return p, srv
+}
+
+// get requests a path and returns the status and body
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProxyStates(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	}))
+	defer app.Close()
+
+	buildErr := errors.New("main.go:3: undefined: <x>")
+
+	tests := []struct {
+		name       string
+		process    string
+		before     []events.Event
+		after      []events.Event
+		wantStatus int
+		wantBody   string
+		minDelay   time.Duration
+	}{
+		{
+			name:       "ready forwards",
+			wantStatus: http.StatusOK,
+			wantBody:   "app",
+		},
+		{
+			name:       "build holds until the process is ready",
+			before:     []events.Event{events.BuildStarted{}},
+			after:      []events.Event{events.BuildSucceeded{}, events.ProcessReady{Name: "app", PID: 2}},
+			wantStatus: http.StatusOK,
+			wantBody:   "app",
+			minDelay:   100 * time.Millisecond,
+		},
+		{
+			name:       "failed build shows the error",
+			before:     []events.Event{events.BuildStarted{}},
+			after:      []events.Event{events.BuildFailed{Err: buildErr}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "undefined: &lt;x&gt;",
+			minDelay:   100 * time.Millisecond,
+		},
+		{
+			name:       "hold times out",
+			before:     []events.Event{events.BuildStarted{}},
+			wantStatus: http.StatusGatewayTimeout,
+			minDelay:   testHoldTimeout,
+		},
+		{
+			name:       "serving process exits",
+			before:     []events.Event{events.ProcessReady{Name: "app", PID: 1}, events.ProcessExited{Name: "app", PID: 1}},
+			after:      []events.Event{events.ProcessReady{Name: "app", PID: 2}},
+			wantStatus: http.StatusOK,
+			minDelay:   100 * time.Millisecond,
+		},
+		{
+			name:       "other process exits",
+			before:     []events.Event{events.ProcessReady{Name: "app", PID: 1}, events.ProcessExited{Name: "app", PID: 7}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "start fails without a serving process",
+			before:     []events.Event{events.ProcessStartFailed{Name: "app", PID: 1, Err: errors.New("probe failed")}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "probe failed",
+		},
+		{
+			name: "start fails while the previous process serves",
+			before: []events.Event{
+				events.ProcessReady{Name: "app", PID: 1},
+				events.ProcessStartFailed{Name: "app", PID: 2, Err: errors.New("probe failed")},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "events of other processes are ignored",
+			process:    "web",
+			before:     []events.Event{events.ProcessReady{Name: "web", PID: 1}, events.ProcessStopping{Name: "worker", PID: 2}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "tracked process stops",
+			process:    "web",
+			before:     []events.Event{events.ProcessReady{Name: "web", PID: 1}, events.ProcessStopping{Name: "web", PID: 1}},
+			after:      []events.Event{events.ProcessReady{Name: "worker", PID: 3}},
+			wantStatus: http.StatusGatewayTimeout,
+			minDelay:   testHoldTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, srv := newTestProxy(t, app.URL, tt.process)
+			p.SetPageScript("<script>reload()</script>")
+			for _, e := range tt.before {
+				p.HandleEvent(e)
+			}
+			if len(tt.after) > 0 {
+				time.AfterFunc(100*time.Millisecond, func() {
+					for _, e := range tt.after {
+						p.HandleEvent(e)
+					}
+				})
+			}
+
+			start := time.Now()
+			status, body := get(t, srv.URL)
+			if elapsed := time.Since(start); elapsed < tt.minDelay {
+				t.Errorf("answered after %v, want at least %v", elapsed, tt.minDelay)
+			}
+			if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+				t.Errorf("got %d %q, want %d containing %q", status, body, tt.wantStatus, tt.wantBody)
+			}
+			if status == http.StatusServiceUnavailable && !strings.Contains(body, "<script>reload()</script>") {
+				t.Errorf("error page lacks the page script: %q", body)
+			}
+		})
+	}
+}
+
+func TestProxyRetriesDial(t *testing.T) {
+	// Reserve a port the application is not listening on yet
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	tests := []struct {
+		name       string
+		startAfter time.Duration
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "application comes up", startAfter: 100 * time.Millisecond, wantStatus: http.StatusOK, wantBody: "posted body"},
+		{name: "application stays down", wantStatus: http.StatusBadGateway, wantBody: "Application unreachable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.startAfter > 0 {
+				app := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					// The body is replayed on each attempt
+					io.Copy(w, r.Body)
+				})}
+				time.AfterFunc(tt.startAfter, func() {
+					if l, err := net.Listen("tcp", addr); err == nil {
+						go app.Serve(l)
+					}
+				})
+				defer app.Close()
+			}
+
+			_, srv := newTestProxy(t, "http://"+addr, "")
+			resp, err := http.Post(srv.URL, "text/plain", strings.NewReader("posted body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("got %d %q, want %d containing %q", resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProxyMounts(t *testing.T) {
+	p, srv := newTestProxy(t, "http://127.0.0.1:1", "")
+	p.Handle("/__own/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "own")
+	}))
+	// The proxy's own handlers answer even while requests are held
+	p.HandleEvent(events.BuildStarted{})
+
+	if status, body := get(t, srv.URL+"/__own/x"); status != http.StatusOK || body != "own" {
+		t.Errorf("got %d %q, want the mounted handler", status, body)
+	}
+}
+
+func TestProxyEssentialEvents(t *testing.T) {
+	p, _ := newTestProxy(t, "http://127.0.0.1:1", "")
+	tests := []struct {
+		event events.Event
+		want  bool
+	}{
+		{event: events.BuildStarted{}, want: true},
+		{event: events.ProcessReady{}, want: true},
+		{event: events.ProcessExited{}, want: true},
+		{event: events.FileChanged{}},
+		{event: events.CacheHit{}},
+	}
+	for _, tt := range tests {
+		if got := p.Essential(tt.event); got != tt.want {
+			t.Errorf("Essential(%T) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package readiness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/config"
+)
+
+const (
+	// DefaultTimeout is used for probes that do not set their own timeout
+	DefaultTimeout = 10 * time.Second
+	// DefaultInterval is the delay between attempts of polling probes
+	DefaultInterval = 200 * time.Millisecond
+)
+
+// Probe checks whether a freshly started process is ready
+type Probe interface {
+	Name() string
+	Wait(ctx context.Context) error
+}
+
+// HTTPProbe waits for an HTTP GET to return a 2xx status
+type HTTPProbe struct {
+	URL      string
+	Interval time.Duration
+	client   *http.Client
+}
+
+// NewHTTPProbe creates an HTTP probe
+func NewHTTPProbe(url string, interval time.Duration) *HTTPProbe {
+	return &HTTPProbe{
+		URL:      url,
+		Interval: interval,
+		client:   &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// Name returns the probe description
+func (p *HTTPProbe) Name() string {
+	return "http " + p.URL
+}
+
+// Wait polls the URL until it answers with 2xx or ctx ends
+func (p *HTTPProbe) Wait(ctx context.Context) error {
+	var lastErr error
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := p.client.Do(req)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			lastErr = fmt.Errorf("status %s", resp.Status)
+		} else {
+			lastErr = err
+		}
+
+		if err := sleep(ctx, p.Interval); err != nil {
+			return fmt.Errorf("%w (last error: %v)", err, lastErr)
+		}
+	}
+}
+
+// TCPProbe waits for a TCP address to accept connections
+type TCPProbe struct {
+	Address  string
+	Interval time.Duration
+}
+
+// NewTCPProbe creates a TCP probe
+func NewTCPProbe(address string, interval time.Duration) *TCPProbe {
+	return &TCPProbe{
+		Address:  address,
+		Interval: interval,
+	}
+}
+
+// Name returns the probe description
+func (p *TCPProbe) Name() string {
+	return "tcp " + p.Address
+}
+
+// Wait dials the address until a connection succeeds or ctx ends
+func (p *TCPProbe) Wait(ctx context.Context) error {
+	var dialer net.Dialer
+	var lastErr error
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		lastErr = err
+
+		if err := sleep(ctx, p.Interval); err != nil {
+			return fmt.Errorf("%w (last error: %v)", err, lastErr)
+		}
+	}
+}
+
+// LogProbe waits for a line of process output to match a pattern.
+// It is an io.Writer that the process output is copied to.
+type LogProbe struct {
+	pattern *regexp.Regexp
+	mu      sync.Mutex
+	partial []byte
+	matched chan struct{}
+	once    sync.Once
+}
+
+// NewLogProbe creates a log pattern probe
+func NewLogProbe(pattern string) (*LogProbe, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log pattern: %w", err)
+	}
+	return &LogProbe{
+		pattern: re,
+		matched: make(chan struct{}),
+	}, nil
+}
+
+// Name returns the probe description
+func (p *LogProbe) Name() string {
+	return "log /" + p.pattern.String() + "/"
+}
+
+// Write scans complete output lines for the pattern
+func (p *LogProbe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.matched:
+		return len(b), nil
+	default:
+	}
+
+	p.partial = append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := p.partial[:i]
+		p.partial = p.partial[i+1:]
+		if p.pattern.Match(line) {
+			p.once.Do(func() { close(p.matched) })
+			p.partial = nil
+			break
+		}
+	}
+
+	return len(b), nil
+}
+
+// Wait blocks until a matching line is written or ctx ends
+func (p *LogProbe) Wait(ctx context.Context) error {
+	select {
+	case <-p.matched:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// check pairs a probe with its timeout
+type check struct {
+	probe   Probe
+	timeout time.Duration
+}
+
+// Checker runs the configured probes for one process start
+type Checker struct {
+	checks []check
+	output []io.Writer
+}
+
+// NewChecker builds fresh probes from the readiness config
+func NewChecker(cfgs []config.ProbeConfig) (*Checker, error) {
+	c := &Checker{}
+
+	for _, pc := range cfgs {
+		interval := pc.Interval.Duration
+		if interval <= 0 {
+			interval = DefaultInterval
+		}
+		timeout := pc.Timeout.Duration
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+
+		var probe Probe
+		switch pc.Type {
+		case "http":
+			probe = NewHTTPProbe(pc.Target, interval)
+		case "tcp":
+			probe = NewTCPProbe(pc.Target, interval)
+		case "log":
+			lp, err := NewLogProbe(pc.Target)
+			if err != nil {
+				return nil, err
+			}
+			c.output = append(c.output, lp)
+			probe = lp
+		default:
+			return nil, fmt.Errorf("unknown readiness probe type %q", pc.Type)
+		}
+
+		c.checks = append(c.checks, check{probe: probe, timeout: timeout})
+	}
+
+	return c, nil
+}
+
+// Empty reports whether no probes are configured
+func (c *Checker) Empty() bool {
+	return len(c.checks) == 0
+}
+
+// OutputWriters returns the writers that must receive the process output
+func (c *Checker) OutputWriters() []io.Writer {
+	return c.output
+}
+
+// Wait runs all probes concurrently and returns the first failure. It
+// gives up as soon as ctx ends, such as when the process exits, returning
+// the cause ctx was cancelled with.
+func (c *Checker) Wait(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(c.checks))
+
+	for _, ch := range c.checks {
+		go func(ch check) {
+			ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+			defer cancel()
+
+			if err := ch.probe.Wait(ctx); err != nil {
+				errs <- fmt.Errorf("readiness probe %s failed after %v: %w", ch.probe.Name(), ch.timeout, err)
+				return
+			}
+			errs <- nil
+		}(ch)
+	}
+
+	var firstErr error
+	for range c.checks {
+		select {
+		case err := <-errs:
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	return firstErr
+}
+
+// sleep waits for d or until ctx ends
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,134 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"hotreloader/pkg/config"
+)
+
+func TestLogProbe(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   bool
+	}{
+		{name: "matching line", writes: []string{"starting\n", "listening on :8080\n"}, want: true},
+		{name: "line split across writes", writes: []string{"listen", "ing on :8080\n"}, want: true},
+		{name: "incomplete line", writes: []string{"listening on :8080"}},
+		{name: "no match", writes: []string{"starting\n", "crashed\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewLogProbe(`^listening on`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.writes {
+				p.Write([]byte(w))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if got := p.Wait(ctx) == nil; got != tt.want {
+				t.Errorf("ready = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckerWait(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	open := l.Addr().String()
+
+	// A port that was listening and is now closed refuses connections
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := closed.Addr().String()
+	closed.Close()
+
+	short := config.Duration{Duration: 200 * time.Millisecond}
+	exited := errors.New("process exited before becoming ready (exit code 1)")
+
+	tests := []struct {
+		name     string
+		probes   []config.ProbeConfig
+		exit     bool
+		wantErr  string
+		maxDelay time.Duration
+	}{
+		{
+			name:     "no probes",
+			maxDelay: 50 * time.Millisecond,
+		},
+		{
+			name:     "tcp probe passes",
+			probes:   []config.ProbeConfig{{Type: "tcp", Target: open, Timeout: short}},
+			maxDelay: 150 * time.Millisecond,
+		},
+		{
+			name:     "probe times out",
+			probes:   []config.ProbeConfig{{Type: "tcp", Target: refused, Timeout: short}},
+			wantErr:  "readiness probe tcp " + refused + " failed after 200ms",
+			maxDelay: time.Second,
+		},
+		{
+			name:     "first failure wins",
+			probes:   []config.ProbeConfig{{Type: "tcp", Target: open, Timeout: short}, {Type: "log", Target: "ready", Timeout: short}},
+			wantErr:  "readiness probe log /ready/ failed",
+			maxDelay: time.Second,
+		},
+		{
+			name:     "process exit ends the wait",
+			probes:   []config.ProbeConfig{{Type: "log", Target: "ready", Timeout: config.Duration{Duration: 10 * time.Second}}},
+			exit:     true,
+			wantErr:  exited.Error(),
+			maxDelay: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewChecker(tt.probes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			if tt.exit {
+				time.AfterFunc(50*time.Millisecond, func() { cancel(exited) })
+			}
+
+			start := time.Now()
+			err = c.Wait(ctx)
+			if elapsed := time.Since(start); elapsed > tt.maxDelay {
+				t.Errorf("Wait took %v, want at most %v", elapsed, tt.maxDelay)
+			}
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Wait: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Wait error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewCheckerRejectsUnknownProbe(t *testing.T) {
+	if _, err := NewChecker([]config.ProbeConfig{{Type: "udp", Target: ":53"}}); err == nil {
+		t.Error("NewChecker accepted an unknown probe type")
+	}
+}
@@ -0,0 +1,316 @@
+package testrunner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/pattern"
+)
+
+// CoverageFileName is the file in the cache directory holding the coverage map
+const CoverageFileName = "coverage.json"
+
+// CoverageMap maps the source files of a module to the tests that execute
+// them. It is built from per-test coverage profiles, one package at a time.
+type CoverageMap struct {
+	mu    sync.RWMutex
+	path  string
+	data  coverageData
+	files map[string]map[string][]string
+}
+
+// coverageData is the persisted form of the map. Tests maps each package
+// to its top-level tests and the files each of them covers; Files lists
+// every instrumented file, covered or not.
+type coverageData struct {
+	Packages map[string]time.Time           `json:"packages"`
+	Tests    map[string]map[string][]string `json:"tests"`
+	Files    []string                       `json:"files"`
+}
+
+// NewCoverageMap creates an empty coverage map saved to path
+func NewCoverageMap(path string) *CoverageMap {
+	m := &CoverageMap{
+		path: path,
+		data: coverageData{
+			Packages: make(map[string]time.Time),
+			Tests:    make(map[string]map[string][]string),
+		},
+	}
+	m.index()
+	return m
+}
+
+// LoadCoverageMap reads the coverage map from path. A missing file yields
+// an empty map.
+func LoadCoverageMap(path string) (*CoverageMap, error) {
+	m := NewCoverageMap(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read coverage map: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &m.data); err != nil {
+			return nil, fmt.Errorf("failed to parse coverage map: %w", err)
+		}
+		if m.data.Packages == nil {
+			m.data.Packages = make(map[string]time.Time)
+		}
+		if m.data.Tests == nil {
+			m.data.Tests = make(map[string]map[string][]string)
+		}
+		m.index()
+	}
+	return m, nil
+}
+
+// index rebuilds the file to tests index from the persisted data
+func (m *CoverageMap) index() {
+	m.files = make(map[string]map[string][]string)
+	for _, file := range m.data.Files {
+		m.files[file] = make(map[string][]string)
+	}
+	for pkg, tests := range m.data.Tests {
+		for test, files := range tests {
+			for _, file := range files {
+				if m.files[file] == nil {
+					m.files[file] = make(map[string][]string)
+				}
+				m.files[file][pkg] = append(m.files[file][pkg], test)
+			}
+		}
+	}
+	for _, byPkg := range m.files {
+		for _, tests := range byPkg {
+			sort.Strings(tests)
+		}
+	}
+}
+
+// Has reports whether coverage was collected for a package
+func (m *CoverageMap) Has(pkg string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.data.Packages[pkg]
+	return ok
+}
+
+// Len returns the number of packages with collected coverage
+func (m *CoverageMap) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.data.Packages)
+}
+
+// Tests returns the tests of the given packages that cover file, keyed by
+// package. It reports false when the file was not instrumented or one of
+// the packages has no coverage yet, in which case the map cannot tell.
+func (m *CoverageMap) Tests(file string, packages []string) (map[string][]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byPkg, known := m.files[file]
+	if !known {
+		return nil, false
+	}
+
+	selected := make(map[string][]string)
+	for _, pkg := range packages {
+		if _, ok := m.data.Packages[pkg]; !ok {
+			return nil, false
+		}
+		if tests := byPkg[pkg]; len(tests) > 0 {
+			selected[pkg] = tests
+		}
+	}
+	return selected, true
+}
+
+// Update replaces the coverage of a package's tests with freshly collected
+// data, given as the files covered by each test, and the instrumented files
+func (m *CoverageMap) Update(pkg string, tests map[string][]string, instrumented []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.Packages[pkg] = time.Now()
+	m.data.Tests[pkg] = tests
+
+	known := make(map[string]bool, len(m.data.Files))
+	for _, file := range m.data.Files {
+		known[file] = true
+	}
+	for _, file := range instrumented {
+		if !known[file] {
+			known[file] = true
+			m.data.Files = append(m.data.Files, file)
+		}
+	}
+	sort.Strings(m.data.Files)
+
+	m.index()
+}
+
+// Save writes the map to its file
+func (m *CoverageMap) Save() error {
+	m.mu.RLock()
+	data, err := json.MarshalIndent(m.data, "", "  ")
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	// Write then rename so a crash never leaves a truncated map
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save coverage map: %w", err)
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// testNamePattern matches the top-level tests, examples and fuzz targets
+// listed by a test binary
+var testNamePattern = regexp.MustCompile(`^(Test|Example|Fuzz)`)
+
+// CollectCoverage runs each top-level test of a package on its own with
+// coverage of the whole module. It returns the project-relative files each
+// test executes and every instrumented file.
+func (r *Runner) CollectCoverage(g *analyzer.GoGraph, importPath string) (map[string][]string, []string, error) {
+	pkg, ok := g.Package(importPath)
+	if !ok || !pkg.HasTests() {
+		return nil, nil, fmt.Errorf("package %s has no tests", importPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	tmp, err := os.MkdirTemp("", "hotreloader-cover-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// Build the test binary once and run it for each test
+	bin := filepath.Join(tmp, "pkg.test")
+	args := append([]string{"test", "-c", "-cover", "-coverpkg=./..."}, r.tagArgs()...)
+	build := exec.CommandContext(ctx, "go", append(args, "-o", bin, importPath)...)
+	build.Dir = r.dir
+	if out, err := build.CombinedOutput(); err != nil {
+		return nil, nil, fmt.Errorf("failed to build test binary: %w\nOutput: %s", err, out)
+	}
+
+	list := exec.CommandContext(ctx, bin, "-test.list", ".")
+	list.Dir = pkg.Dir
+	out, err := list.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tests: %w", err)
+	}
+
+	tests := make(map[string][]string)
+	instrumented := make(map[string]bool)
+	profile := filepath.Join(tmp, "cover.out")
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if !testNamePattern.MatchString(name) {
+			continue
+		}
+
+		os.Remove(profile)
+		run := exec.CommandContext(ctx, bin, "-test.run", runPattern([]string{name}), "-test.coverprofile", profile)
+		run.Dir = pkg.Dir
+		// A failing test still writes its profile
+		run.Run()
+		if ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("coverage collection timed out after %v", r.timeout)
+		}
+
+		covered, err := r.readProfile(g, profile, instrumented)
+		if err != nil {
+			continue
+		}
+		tests[name] = covered
+	}
+
+	files := make([]string, 0, len(instrumented))
+	for file := range instrumented {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return tests, files, nil
+}
+
+// readProfile parses a coverage profile, returning the project-relative
+// files with at least one executed block and adding every file it lists to
+// instrumented
+func (r *Runner) readProfile(g *analyzer.GoGraph, profile string, instrumented map[string]bool) ([]string, error) {
+	f, err := os.Open(profile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Profile files are named by import path, as in "mod/pkg/file.go"
+	rel := make(map[string]string)
+	covered := make(map[string]bool)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// Lines have the form "file:start,end statements count"
+		colon := strings.LastIndex(line, ":")
+		fields := strings.Fields(line)
+		if colon < 0 || len(fields) != 3 {
+			continue
+		}
+		name := line[:colon]
+
+		file, ok := rel[name]
+		if !ok {
+			if pkg, found := g.Package(path.Dir(name)); found {
+				file = pattern.Rel(r.dir, filepath.Join(pkg.Dir, path.Base(name)))
+			}
+			rel[name] = file
+		}
+		if file == "" {
+			continue
+		}
+
+		instrumented[file] = true
+		if fields[2] != "0" {
+			covered[file] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(covered))
+	for file := range covered {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
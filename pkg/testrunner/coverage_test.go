@@ -0,0 +1,211 @@
+package testrunner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"hotreloader/pkg/analyzer"
+	"hotreloader/pkg/config"
+)
+
+// newTestCoverageMap returns a map with coverage of two packages, where
+// TestA and TestShared cover a.go and TestB covers b.go
+func newTestCoverageMap(path string) *CoverageMap {
+	m := NewCoverageMap(path)
+	m.Update("mod/a", map[string][]string{
+		"TestA":      {"a/a.go"},
+		"TestShared": {"a/a.go", "b/b.go"},
+	}, []string{"a/a.go", "b/b.go", "c/c.go"})
+	m.Update("mod/b", map[string][]string{
+		"TestB": {"b/b.go"},
+	}, []string{"b/b.go"})
+	return m
+}
+
+func TestCoverageMapTests(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		packages []string
+		want     map[string][]string
+		wantOK   bool
+	}{
+		{
+			name:     "tests of every package covering a file",
+			file:     "b/b.go",
+			packages: []string{"mod/a", "mod/b"},
+			want:     map[string][]string{"mod/a": {"TestShared"}, "mod/b": {"TestB"}},
+			wantOK:   true,
+		},
+		{
+			name:     "only the given packages",
+			file:     "a/a.go",
+			packages: []string{"mod/a"},
+			want:     map[string][]string{"mod/a": {"TestA", "TestShared"}},
+			wantOK:   true,
+		},
+		{
+			name:     "instrumented file no test covers",
+			file:     "c/c.go",
+			packages: []string{"mod/a", "mod/b"},
+			want:     map[string][]string{},
+			wantOK:   true,
+		},
+		{
+			name:     "file never instrumented",
+			file:     "d/d.go",
+			packages: []string{"mod/a"},
+		},
+		{
+			name:     "package without coverage",
+			file:     "a/a.go",
+			packages: []string{"mod/a", "mod/c"},
+		},
+	}
+
+	m := newTestCoverageMap(filepath.Join(t.TempDir(), CoverageFileName))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Tests(tt.file, tt.packages)
+			if ok != tt.wantOK {
+				t.Fatalf("Tests(%q) ok = %v, want %v", tt.file, ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tests(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverageMapUpdateReplacesPackage(t *testing.T) {
+	m := newTestCoverageMap(filepath.Join(t.TempDir(), CoverageFileName))
+	m.Update("mod/a", map[string][]string{"TestA": {"a/a.go"}}, []string{"a/a.go"})
+
+	got, ok := m.Tests("b/b.go", []string{"mod/a", "mod/b"})
+	want := map[string][]string{"mod/b": {"TestB"}}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("Tests after update = %v, %v, want %v", got, ok, want)
+	}
+}
+
+func TestCoverageMapSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", CoverageFileName)
+	m := newTestCoverageMap(path)
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadCoverageMap(path)
+	if err != nil {
+		t.Fatalf("LoadCoverageMap: %v", err)
+	}
+	if loaded.Len() != 2 || !loaded.Has("mod/a") || !loaded.Has("mod/b") {
+		t.Fatalf("loaded %d packages, want mod/a and mod/b", loaded.Len())
+	}
+
+	for _, file := range []string{"a/a.go", "b/b.go", "c/c.go"} {
+		want, _ := m.Tests(file, []string{"mod/a", "mod/b"})
+		got, ok := loaded.Tests(file, []string{"mod/a", "mod/b"})
+		if !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("loaded Tests(%q) = %v, %v, want %v", file, got, ok, want)
+		}
+	}
+}
+
+func TestLoadCoverageMapMissing(t *testing.T) {
+	m, err := LoadCoverageMap(filepath.Join(t.TempDir(), CoverageFileName))
+	if err != nil {
+		t.Fatalf("LoadCoverageMap: %v", err)
+	}
+	if m.Len() != 0 {
+		t.Errorf("Len = %d, want 0", m.Len())
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.22\n",
+		"lib/a.go":        "package lib\n",
+		"lib/b.go":        "package lib\n",
+		"lib/lib_test.go": "package lib\n",
+		"cmd/app/main.go": "package main\n",
+	})
+	g, err := analyzer.LoadGoGraph(dir, analyzer.NewGoTarget("", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Profiles as written by -test.coverprofile for each test of lib
+	profiles := map[string]string{
+		"TestA": `mode: set
+example.com/m/lib/a.go:3.20,5.2 1 1
+example.com/m/lib/b.go:3.20,5.2 1 0
+example.com/m/cmd/app/main.go:3.13,5.2 1 0
+`,
+		"TestBoth": `mode: set
+example.com/m/lib/a.go:3.20,5.2 1 0
+example.com/m/lib/a.go:7.20,9.2 2 1
+example.com/m/lib/b.go:3.20,5.2 1 1
+other.com/dep/dep.go:1.1,2.2 1 1
+malformed line
+`,
+		"TestNone": `mode: set
+example.com/m/lib/a.go:3.20,5.2 1 0
+`,
+	}
+
+	r := NewRunner(dir, config.TestConfig{})
+	tests := make(map[string][]string)
+	instrumented := make(map[string]bool)
+	for name, content := range profiles {
+		profile := filepath.Join(t.TempDir(), "cover.out")
+		if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		covered, err := r.readProfile(g, profile, instrumented)
+		if err != nil {
+			t.Fatalf("readProfile(%s): %v", name, err)
+		}
+		tests[name] = covered
+	}
+
+	wantTests := map[string][]string{
+		"TestA":    {"lib/a.go"},
+		"TestBoth": {"lib/a.go", "lib/b.go"},
+		"TestNone": {},
+	}
+	if !reflect.DeepEqual(tests, wantTests) {
+		t.Errorf("covered files = %v, want %v", tests, wantTests)
+	}
+	wantInstrumented := map[string]bool{"lib/a.go": true, "lib/b.go": true, "cmd/app/main.go": true}
+	if !reflect.DeepEqual(instrumented, wantInstrumented) {
+		t.Errorf("instrumented = %v, want %v", instrumented, wantInstrumented)
+	}
+
+	// The parsed profiles select tests by the file they cover
+	files := make([]string, 0, len(instrumented))
+	for file := range instrumented {
+		files = append(files, file)
+	}
+	m := NewCoverageMap(filepath.Join(t.TempDir(), CoverageFileName))
+	m.Update("example.com/m/lib", tests, files)
+	for file, want := range map[string][]string{
+		"lib/a.go":        {"TestA", "TestBoth"},
+		"lib/b.go":        {"TestBoth"},
+		"cmd/app/main.go": nil,
+	} {
+		got, ok := m.Tests(file, []string{"example.com/m/lib"})
+		if !ok || !reflect.DeepEqual(got["example.com/m/lib"], want) {
+			t.Errorf("Tests(%q) = %v, %v, want %v", file, got, ok, want)
+		}
+	}
+}
+
+func TestReadProfileMissing(t *testing.T) {
+	r := NewRunner(t.TempDir(), config.TestConfig{})
+	if _, err := r.readProfile(nil, filepath.Join(t.TempDir(), "cover.out"), map[string]bool{}); err == nil {
+		t.Error("readProfile succeeded without a profile")
+	}
+}
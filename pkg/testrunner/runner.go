@@ -0,0 +1,306 @@
+package testrunner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hotreloader/pkg/config"
+	"hotreloader/pkg/events"
+)
+
+// DefaultTimeout bounds a whole test run
+const DefaultTimeout = 10 * time.Minute
+
+// Result summarizes a test run. Counts cover top-level tests; Failures
+// lists the innermost failing tests and packages that failed to build.
+type Result struct {
+	Packages []string
+	Passed   int
+	Failed   int
+	Skipped  int
+	Failures []events.TestFailure
+	Duration time.Duration
+}
+
+// Runner runs `go test` on a set of packages
+type Runner struct {
+	dir      string
+	parallel int
+	args     []string
+	tags     []string
+	timeout  time.Duration
+}
+
+// testEvent is a line of `go test -json` output
+type testEvent struct {
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Output     string
+}
+
+// NewRunner creates a test runner for the module in dir
+func NewRunner(dir string, cfg config.TestConfig) *Runner {
+	parallel := cfg.Parallel
+	if parallel <= 0 {
+		parallel = runtime.GOMAXPROCS(0)
+	}
+	timeout := cfg.Timeout.Duration
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Runner{
+		dir:      dir,
+		parallel: parallel,
+		args:     cfg.Args,
+		timeout:  timeout,
+	}
+}
+
+// SetTags sets the extra build tags tests are built with
+func (r *Runner) SetTags(tags []string) {
+	r.tags = tags
+}
+
+// tagArgs returns the go command flags selecting the build tags
+func (r *Runner) tagArgs() []string {
+	if len(r.tags) == 0 {
+		return nil
+	}
+	return []string{"-tags", strings.Join(r.tags, ",")}
+}
+
+// Run tests the packages in parallel and collects the results. Failing
+// tests are reported in the result, not as an error.
+func (r *Runner) Run(packages []string) (*Result, error) {
+	start := time.Now()
+	result := &Result{Packages: packages}
+	if len(packages) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	args := append([]string{"-p", strconv.Itoa(r.parallel)}, packages...)
+	c := newCollector()
+	if err := r.goTest(ctx, args, c); err != nil {
+		return nil, err
+	}
+
+	c.fill(result)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// RunSelected runs only the named top-level tests of each package, testing
+// the packages in parallel
+func (r *Runner) RunSelected(tests map[string][]string) (*Result, error) {
+	start := time.Now()
+	result := &Result{}
+	for pkg := range tests {
+		result.Packages = append(result.Packages, pkg)
+	}
+	sort.Strings(result.Packages)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	c := newCollector()
+	sem := make(chan struct{}, r.parallel)
+	errs := make(chan error, len(tests))
+	var wg sync.WaitGroup
+
+	for pkg, names := range tests {
+		wg.Add(1)
+		go func(pkg string, names []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			args := []string{"-run", runPattern(names), pkg}
+			if err := r.goTest(ctx, args, c); err != nil {
+				errs <- err
+			}
+		}(pkg, names)
+	}
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	c.fill(result)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// goTest runs `go test -json` with args and feeds its events to c. A
+// non-zero exit is only an error when no failure was reported.
+func (r *Runner) goTest(ctx context.Context, args []string, c *collector) error {
+	args = append(append(append([]string{"test", "-json"}, r.tagArgs()...), r.args...), args...)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = r.dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run go test: %w", err)
+	}
+
+	failures := false
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev testEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		if ev.Action == "fail" {
+			failures = true
+		}
+		c.add(ev)
+	}
+
+	waitErr := cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("go test timed out after %v", r.timeout)
+	}
+	if waitErr != nil && !failures {
+		return fmt.Errorf("go test failed: %w\nOutput: %s", waitErr, stderr.String())
+	}
+	return nil
+}
+
+// runPattern returns a -run pattern matching exactly the named tests
+func runPattern(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// collector accumulates `go test -json` events, possibly from several
+// concurrent runs
+type collector struct {
+	mu          sync.Mutex
+	output      map[string][]string
+	buildOutput map[string][]string
+	failed      map[string][]string
+	failedPkgs  []string
+	order       []string
+	passed      int
+	skipped     int
+}
+
+// newCollector creates an empty collector
+func newCollector() *collector {
+	return &collector{
+		output:      make(map[string][]string),
+		buildOutput: make(map[string][]string),
+		failed:      make(map[string][]string),
+	}
+}
+
+// add records one event
+func (c *collector) add(ev testEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ev.Package + " " + ev.Test
+	topLevel := ev.Test != "" && !strings.Contains(ev.Test, "/")
+
+	switch ev.Action {
+	case "output":
+		c.output[key] = append(c.output[key], strings.TrimRight(ev.Output, "\n"))
+	case "build-output":
+		// The import path may name the test variant, as in "p [p.test]"
+		pkg, _, _ := strings.Cut(ev.ImportPath, " ")
+		c.buildOutput[pkg] = append(c.buildOutput[pkg], strings.TrimRight(ev.Output, "\n"))
+	case "pass":
+		if topLevel {
+			c.passed++
+		}
+	case "skip":
+		if topLevel {
+			c.skipped++
+		}
+	case "fail":
+		if ev.Test != "" {
+			c.failed[key] = []string{ev.Package, ev.Test}
+			c.order = append(c.order, key)
+		} else {
+			c.failedPkgs = append(c.failedPkgs, ev.Package)
+		}
+	}
+}
+
+// fill writes the counts and failures into the result
+func (c *collector) fill(result *Result) {
+	result.Passed = c.passed
+	result.Skipped = c.skipped
+
+	failedTests := make(map[string]bool)
+	for _, key := range c.order {
+		pkg, test := c.failed[key][0], c.failed[key][1]
+		failedTests[pkg] = true
+		if !strings.Contains(test, "/") {
+			result.Failed++
+		}
+
+		// A parent fails with its subtests; report only the innermost failure
+		if c.hasFailedSubtest(pkg, test) {
+			continue
+		}
+		result.Failures = append(result.Failures, events.TestFailure{
+			Package: pkg,
+			Test:    test,
+			Output:  c.output[key],
+		})
+	}
+
+	// Packages that failed without a failing test did not build or panicked
+	for _, pkg := range c.failedPkgs {
+		if failedTests[pkg] {
+			continue
+		}
+		output := c.buildOutput[pkg]
+		if len(output) == 0 {
+			output = c.output[pkg+" "]
+		}
+		result.Failures = append(result.Failures, events.TestFailure{
+			Package: pkg,
+			Output:  output,
+		})
+	}
+}
+
+// hasFailedSubtest reports whether a subtest of test also failed
+func (c *collector) hasFailedSubtest(pkg, test string) bool {
+	prefix := pkg + " " + test + "/"
+	for key := range c.failed {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package testrunner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"hotreloader/pkg/config"
+)
+
+// writeModule creates a module from a map of relative paths to content
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunTags(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+
+	dir := writeModule(t, map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.22\n",
+		"lib/lib.go":      "package lib\n",
+		"lib/lib_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestPlain(t *testing.T) {}\n",
+		"lib/tagged_test.go": "//go:build special\n\npackage lib\n\nimport \"testing\"\n\n" +
+			"func TestTagged(t *testing.T) {}\n\nfunc TestTaggedToo(t *testing.T) {}\n",
+	})
+
+	tests := []struct {
+		name       string
+		tags       []string
+		wantPassed int
+	}{
+		{name: "without tags", wantPassed: 1},
+		{name: "with tags", tags: []string{"special"}, wantPassed: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunner(dir, config.TestConfig{})
+			r.SetTags(tt.tags)
+			result, err := r.Run([]string{"example.com/m/lib"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed != tt.wantPassed || result.Failed != 0 {
+				t.Errorf("passed %d, failed %d, want %d passed", result.Passed, result.Failed, tt.wantPassed)
+			}
+		})
+	}
+}
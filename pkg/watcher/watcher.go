@@ -44,6 +44,7 @@ func NewWatcher(rootDir string, opt *optimizer.Optimizer) (*Watcher, error) {
 			"build",
 			"*.log",
 			".DS_Store",
+			".hotreloader",
 		},
 	}
 
@@ -68,7 +69,7 @@ func (w *Watcher) Start() error {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
-	fmt.Println("\nWatching for changes... (Press Ctrl+C to show stats and exit)\n")
+	fmt.Print("\nWatching for changes... (Press Ctrl+C to show stats and exit)\n\n")
 
 	for {
 		select {